@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	asmFuncLine  = regexp.MustCompile(`^(\S+) STEXT\b(.*)$`)
+	asmInstrLine = regexp.MustCompile(`^\t(0x[0-9a-f]+) \d+ \((.+\.go):(\d+)\)\t(.*)$`)
+	ssaDumpLine  = regexp.MustCompile(`(?m)^dumped SSA for .* to .+$`)
+)
+
+// AsmFunc is the assembly the compiler generated for one function
+type AsmFunc struct {
+	Symbol       string
+	Attributes   string
+	Instructions []AsmInstr
+}
+
+// AsmInstr is an assembly instruction and the source line it came from
+type AsmInstr struct {
+	Offset   string
+	Filename string
+	Line     int
+	Text     string
+}
+
+// CompileAssembly builds the archive in a temporary module with -gcflags=-S
+// and returns the assembly of every function of its packages
+func CompileAssembly(a *Archive) ([]AsmFunc, error) {
+	w, err := a.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), compilerTimeout)
+	defer cancel()
+
+	out, err := w.Command(ctx, "build", "-gcflags=-S", "./...").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("go build: %w\n%s", err, out)
+	}
+	return parseAssembly(a, out), nil
+}
+
+// parseAssembly extracts functions and their instructions from -S output.
+// PCDATA and FUNCDATA pseudo-instructions are dropped.
+func parseAssembly(a *Archive, out []byte) []AsmFunc {
+	var funcs []AsmFunc
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if m := asmFuncLine.FindStringSubmatch(line); m != nil {
+			funcs = append(funcs, AsmFunc{
+				Symbol:     m[1],
+				Attributes: strings.TrimSpace(m[2]),
+			})
+			continue
+		}
+		m := asmInstrLine.FindStringSubmatch(line)
+		if m == nil || len(funcs) == 0 {
+			continue
+		}
+		text := strings.ReplaceAll(m[4], "\t", " ")
+		if strings.HasPrefix(text, "PCDATA") || strings.HasPrefix(text, "FUNCDATA") {
+			continue
+		}
+		n, _ := strconv.Atoi(m[3])
+		f := &funcs[len(funcs)-1]
+		f.Instructions = append(f.Instructions, AsmInstr{
+			Offset:   m[1],
+			Filename: a.archiveFilename(m[2]),
+			Line:     n,
+			Text:     text,
+		})
+	}
+	return funcs
+}
+
+// archiveFilename maps an absolute path in a workspace back to the name of
+// the archive file it was written from, or returns it unchanged
+func (a *Archive) archiveFilename(path string) string {
+	path = filepath.ToSlash(path)
+	best := ""
+	for _, file := range a.Txtar.Files {
+		if strings.HasSuffix(path, "/"+file.Name) && len(file.Name) > len(best) {
+			best = file.Name
+		}
+	}
+	if best == "" {
+		return path
+	}
+	return best
+}
+
+// asmFuncsOf returns the compiled functions whose code starts inside a
+// declaration, which includes its closures and generic instantiations
+func (a *Archive) asmFuncsOf(funcs []AsmFunc, decl *ast.FuncDecl) []AsmFunc {
+	start := a.Fset.Position(decl.Pos())
+	end := a.Fset.Position(decl.End())
+
+	var matched []AsmFunc
+	for _, f := range funcs {
+		if len(f.Instructions) == 0 {
+			continue
+		}
+		first := f.Instructions[0]
+		if first.Filename == start.Filename && start.Line <= first.Line && first.Line <= end.Line {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// AssemblyNodes shows the assembly of the selected function declaration,
+// with instructions grouped by the source line they were generated from
+func AssemblyNodes(a *Archive, funcs []AsmFunc, decl *ast.FuncDecl) []*ASTNode {
+	if decl == nil {
+		return []*ASTNode{{
+			Label:       "Select a function declaration in the AST to see its assembly",
+			IndentLevel: 1,
+		}}
+	}
+
+	matched := a.asmFuncsOf(funcs, decl)
+	if len(matched) == 0 {
+		return []*ASTNode{{
+			Label:       fmt.Sprintf("No code was generated for %s; it may be generic or always inlined", decl.Name.Name),
+			IndentLevel: 1,
+		}}
+	}
+
+	var nodes []*ASTNode
+	for _, f := range matched {
+		funcNode := &ASTNode{
+			Label:       fmt.Sprintf("%s (%s)", f.Symbol, f.Attributes),
+			IndentLevel: 1,
+		}
+		var lineNode *ASTNode
+		for i, instr := range f.Instructions {
+			if i == 0 || instr.Line != f.Instructions[i-1].Line || instr.Filename != f.Instructions[i-1].Filename {
+				lineNode = asmLineToNode(a, instr)
+				funcNode.Children = append(funcNode.Children, lineNode)
+			}
+			lineNode.Children = append(lineNode.Children, &ASTNode{
+				Label:       instr.Offset + "  " + instr.Text,
+				IndentLevel: 3,
+				Node:        lineNode.Node,
+			})
+		}
+		nodes = append(nodes, funcNode)
+	}
+	LinkParents(nodes, nil)
+	return nodes
+}
+
+// asmLineToNode shows a source line, linked to the first node on it
+func asmLineToNode(a *Archive, instr AsmInstr) *ASTNode {
+	d := Diagnostic{
+		Filename: instr.Filename,
+		Line:     instr.Line,
+		Column:   1,
+	}
+	lines := splitLines(string(archiveFileData(a.Txtar, instr.Filename)))
+	text := ""
+	if instr.Line >= 1 && instr.Line <= len(lines) {
+		text = strings.TrimSpace(lines[instr.Line-1])
+		d.Column = len(lines[instr.Line-1]) - len(strings.TrimLeft(lines[instr.Line-1], " \t")) + 1
+	}
+	return &ASTNode{
+		Label:       fmt.Sprintf("%s:%d: %s", instr.Filename, instr.Line, text),
+		IndentLevel: 2,
+		Node:        a.displayedNodeAt(a.diagnosticPos(d)),
+	}
+}
+
+// DumpSSA builds the archive with GOSSAFUNC set to a compiled function's
+// symbol and returns the path of a copy of the ssa.html the compiler wrote.
+// All packages are recompiled the first time, which can take a while.
+func DumpSSA(a *Archive, symbol string) (string, error) {
+	w, err := a.NewWorkspace()
+	if err != nil {
+		return "", err
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), compilerTimeout)
+	defer cancel()
+
+	cmd := w.Command(ctx, "build", "./...")
+	cmd.Env = append(cmd.Env, "GOSSAFUNC="+symbol)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("go build: %w\n%s", err, out)
+	}
+
+	if !ssaDumpLine.Match(out) {
+		return "", fmt.Errorf("the compiler did not dump SSA for %s", symbol)
+	}
+
+	// The file is written to the directory of the package being compiled
+	var found string
+	filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && found == "" && d.Name() == "ssa.html" {
+			found = path
+		}
+		return nil
+	})
+	if found == "" {
+		return "", fmt.Errorf("ssa.html for %s not found", symbol)
+	}
+	data, err := os.ReadFile(found)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "ssa-*.html")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
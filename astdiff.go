@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"strings"
+)
+
+var (
+	diffInsertedColor = color.RGBA{R: 0x1a, G: 0x7f, B: 0x37, A: 0xff}
+	diffDeletedColor  = color.RGBA{R: 0xcf, G: 0x22, B: 0x2e, A: 0xff}
+	diffChangedColor  = color.RGBA{R: 0x9a, G: 0x67, B: 0x00, A: 0xff}
+	diffMovedColor    = color.RGBA{R: 0x09, G: 0x69, B: 0xda, A: 0xff}
+)
+
+// astDiffer computes a structural diff between two display trees
+type astDiffer struct {
+	hashes   map[*ASTNode]uint64
+	sizes    map[*ASTNode]int
+	inserted []*ASTNode
+	deleted  []*ASTNode
+	// sources maps merged nodes back to the subtree they were copied from
+	sources map[*ASTNode]*ASTNode
+}
+
+// DiffTrees compares the display trees of two versions of the source and
+// returns a merged tree in which inserted, deleted, moved and changed
+// subtrees are marked and colored. Unchanged subtrees start collapsed.
+func DiffTrees(old, new []*ASTNode) []*ASTNode {
+	d := &astDiffer{
+		hashes:  make(map[*ASTNode]uint64),
+		sizes:   make(map[*ASTNode]int),
+		sources: make(map[*ASTNode]*ASTNode),
+	}
+	merged := d.diffChildren(old, new, 1)
+	d.markMoves()
+	return merged
+}
+
+// hash returns a hash of the labels of a subtree
+func (d *astDiffer) hash(node *ASTNode) uint64 {
+	if h, ok := d.hashes[node]; ok {
+		return h
+	}
+	h := fnv.New64a()
+	h.Write([]byte(node.Label))
+	for _, child := range node.Children {
+		fmt.Fprintf(h, "\x00%x", d.hash(child))
+	}
+	sum := h.Sum64()
+	d.hashes[node] = sum
+	return sum
+}
+
+// diffChildren merges two lists of sibling nodes. Siblings are aligned by
+// label, preferring larger subtrees, so that a changed function stays in
+// place while small unchanged ones around it may show up as moved.
+func (d *astDiffer) diffChildren(old, new []*ASTNode, level int) []*ASTNode {
+	pairs := lcsPairs(len(old), len(new), func(i, j int) int {
+		if old[i].Label != new[j].Label {
+			return 0
+		}
+		return d.size(old[i]) + d.size(new[j])
+	})
+
+	var merged []*ASTNode
+	i, j := 0, 0
+	for _, p := range append(pairs, [2]int{len(old), len(new)}) {
+		merged = append(merged, d.diffGap(old[i:p[0]], new[j:p[1]], level)...)
+		if p[0] < len(old) {
+			merged = append(merged, d.diffPair(old[p[0]], new[p[1]], level))
+		}
+		i, j = p[0]+1, p[1]+1
+	}
+	return merged
+}
+
+// diffPair merges two aligned nodes
+func (d *astDiffer) diffPair(old, new *ASTNode, level int) *ASTNode {
+	if d.hash(old) == d.hash(new) {
+		return d.copyTree(new, level, "", nil, true)
+	}
+
+	label := "~ " + new.Label
+	if old.Label != new.Label {
+		label = fmt.Sprintf("~ %s (was %s)", new.Label, old.Label)
+	}
+	return &ASTNode{
+		Label:       label,
+		Children:    d.diffChildren(old.Children, new.Children, level+1),
+		IndentLevel: level,
+		Node:        new.Node,
+		Color:       diffChangedColor,
+	}
+}
+
+// diffGap merges the unaligned nodes between two aligned pairs. A single node
+// replaced by one of the same kind is shown as changed; everything else is
+// deleted or inserted.
+func (d *astDiffer) diffGap(old, new []*ASTNode, level int) []*ASTNode {
+	if len(old) == 1 && len(new) == 1 && labelKind(old[0].Label) == labelKind(new[0].Label) {
+		return []*ASTNode{d.diffPair(old[0], new[0], level)}
+	}
+
+	var merged []*ASTNode
+	for _, node := range old {
+		copied := d.copyTree(node, level, "- ", diffDeletedColor, false)
+		detach(copied)
+		d.deleted = append(d.deleted, copied)
+		merged = append(merged, copied)
+	}
+	for _, node := range new {
+		copied := d.copyTree(node, level, "+ ", diffInsertedColor, false)
+		d.inserted = append(d.inserted, copied)
+		merged = append(merged, copied)
+	}
+	return merged
+}
+
+// size returns the number of nodes in a subtree
+func (d *astDiffer) size(node *ASTNode) int {
+	if n, ok := d.sizes[node]; ok {
+		return n
+	}
+	n := 1
+	for _, child := range node.Children {
+		n += d.size(child)
+	}
+	d.sizes[node] = n
+	return n
+}
+
+// copyTree copies a subtree into the merged tree
+func (d *astDiffer) copyTree(node *ASTNode, level int, prefix string, c color.Color, collapse bool) *ASTNode {
+	copied := &ASTNode{
+		Label:       prefix + node.Label,
+		IndentLevel: level,
+		Node:        node.Node,
+		Color:       c,
+		Collapsed:   collapse && len(node.Children) > 0,
+	}
+	for _, child := range node.Children {
+		copied.Children = append(copied.Children, d.copyTree(child, level+1, prefix, c, false))
+	}
+	d.sources[copied] = node
+	return copied
+}
+
+// detach clears the syntax nodes of a subtree copied from the old tree,
+// whose positions belong to the snapshot rather than the current archive
+func detach(node *ASTNode) {
+	node.Node = nil
+	for _, child := range node.Children {
+		detach(child)
+	}
+}
+
+// markMoves turns deleted and inserted subtrees with identical content into
+// a move
+func (d *astDiffer) markMoves() {
+	deleted := make(map[uint64][]*ASTNode)
+	for _, node := range d.deleted {
+		h := d.hash(d.sources[node])
+		deleted[h] = append(deleted[h], node)
+	}
+
+	for _, node := range d.inserted {
+		h := d.hash(d.sources[node])
+		candidates := deleted[h]
+		if len(candidates) == 0 {
+			continue
+		}
+		from := candidates[0]
+		deleted[h] = candidates[1:]
+		relabel(from, "- ", "< ", diffMovedColor)
+		from.Label += " (moved away)"
+		relabel(node, "+ ", "> ", diffMovedColor)
+		node.Label += " (moved here)"
+	}
+}
+
+// relabel swaps the marker prefix and color of a subtree
+func relabel(node *ASTNode, oldPrefix, newPrefix string, c color.Color) {
+	node.Label = newPrefix + strings.TrimPrefix(node.Label, oldPrefix)
+	node.Color = c
+	for _, child := range node.Children {
+		relabel(child, oldPrefix, newPrefix, c)
+	}
+}
+
+// labelKind returns the part of a label that names the kind of node, such
+// as "Func" for "Func: main"
+func labelKind(label string) string {
+	if kind, _, ok := strings.Cut(label, ":"); ok {
+		return kind
+	}
+	kind, _, _ := strings.Cut(label, " ")
+	return kind
+}
+
+// lcsPairs returns the index pairs of a heaviest common subsequence of two
+// sequences of lengths n and m, where weight returns how much matching the
+// i-th and j-th elements is worth, or 0 if they do not match
+func lcsPairs(n, m int, weight func(i, j int) int) [][2]int {
+	w := make([][]int, n)
+	for i := range w {
+		w[i] = make([]int, m)
+		for j := range w[i] {
+			w[i][j] = weight(i, j)
+		}
+	}
+
+	// best[i][j] is the heaviest weight achievable for old[i:] and new[j:]
+	best := make([][]int, n+1)
+	for i := range best {
+		best[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			best[i][j] = max(best[i+1][j], best[i][j+1])
+			if w[i][j] > 0 {
+				best[i][j] = max(best[i][j], best[i+1][j+1]+w[i][j])
+			}
+		}
+	}
+
+	var pairs [][2]int
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case w[i][j] > 0 && best[i][j] == best[i+1][j+1]+w[i][j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case best[i+1][j] >= best[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// tree builds a display node for tests
+func tree(label string, children ...*ASTNode) *ASTNode {
+	return &ASTNode{Label: label, Children: children}
+}
+
+// renderTree writes every row of a tree, collapsed or not, one per line and
+// indented by depth
+func renderTree(b *strings.Builder, nodes []*ASTNode, depth int) {
+	for _, node := range nodes {
+		b.WriteString(strings.Repeat("  ", depth) + node.Label + "\n")
+		renderTree(b, node.Children, depth+1)
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []*ASTNode
+		want     string
+	}{
+		{
+			name: "unchanged",
+			old:  []*ASTNode{tree("Func: f", tree("Body"))},
+			new:  []*ASTNode{tree("Func: f", tree("Body"))},
+			want: "Func: f\n  Body\n",
+		},
+		{
+			name: "inserted",
+			old:  []*ASTNode{tree("Const: a"), tree("Const: b")},
+			new:  []*ASTNode{tree("Const: a"), tree("Var: c"), tree("Const: b")},
+			want: "Const: a\n+ Var: c\nConst: b\n",
+		},
+		{
+			name: "deleted",
+			old:  []*ASTNode{tree("Const: a"), tree("Var: c", tree("Type: int")), tree("Const: b")},
+			new:  []*ASTNode{tree("Const: a"), tree("Const: b")},
+			want: "Const: a\n- Var: c\n  - Type: int\nConst: b\n",
+		},
+		{
+			name: "changed in place",
+			old:  []*ASTNode{tree("Func: f", tree("Var: x"), tree("Body"))},
+			new:  []*ASTNode{tree("Func: f", tree("Var: y"), tree("Body"))},
+			want: "~ Func: f\n  ~ Var: y (was Var: x)\n  Body\n",
+		},
+		{
+			name: "moved",
+			old: []*ASTNode{
+				tree("Func: a", tree("Body")),
+				tree("Func: b", tree("Body"), tree("Results")),
+				tree("Func: c", tree("Body"), tree("Results")),
+			},
+			new: []*ASTNode{
+				tree("Func: b", tree("Body"), tree("Results")),
+				tree("Func: c", tree("Body"), tree("Results")),
+				tree("Func: a", tree("Body")),
+			},
+			want: "< Func: a (moved away)\n  < Body\n" +
+				"Func: b\n  Body\n  Results\n" +
+				"Func: c\n  Body\n  Results\n" +
+				"> Func: a (moved here)\n  > Body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			renderTree(&b, DiffTrees(tt.old, tt.new), 0)
+			if got := b.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTreesSource(t *testing.T) {
+	before, err := ParseTxtar("-- a.go --\npackage a\n\nfunc f() {}\n\nfunc g() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseTxtar("-- a.go --\npackage a\n\nfunc f() {}\n\nfunc h() {}\n\nfunc g() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inserted []string
+	for _, node := range FlattenNodes(DiffTrees(before.Nodes, after.Nodes)) {
+		if strings.HasPrefix(node.Label, "+ ") {
+			inserted = append(inserted, node.Label)
+		}
+	}
+	if len(inserted) == 0 || !strings.Contains(inserted[0], "h") {
+		t.Errorf("inserted rows = %q, want the declaration of h first", inserted)
+	}
+}
+
+func TestDiffTreesDetachesOldRows(t *testing.T) {
+	before, err := ParseTxtar("-- a.go --\npackage a\n\nfunc f() {}\n\nfunc g() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseTxtar("-- a.go --\npackage a\n\nfunc g() {}\n\nfunc h() {}\n\nfunc f() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, node := range FlattenNodes(DiffTrees(before.Nodes, after.Nodes)) {
+		old := strings.HasPrefix(node.Label, "- ") || strings.HasPrefix(node.Label, "< ")
+		if old && node.Node != nil {
+			t.Errorf("%q keeps a node of the old archive", node.Label)
+		}
+	}
+}
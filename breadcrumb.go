@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"image"
+	"strings"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// maxCrumbs is the number of ancestors shown in the breadcrumb. Deeper
+// paths keep the file and the innermost ancestors.
+const maxCrumbs = 6
+
+// Breadcrumb shows the path from the file down to the selected node, with
+// a button per ancestor
+type Breadcrumb struct {
+	guigui.DefaultWidget
+
+	elisionText  basicwidget.Text
+	crumbButtons []basicwidget.Button
+	separators   []basicwidget.Text
+
+	path       []*ASTNode
+	onSelected func(node *ASTNode)
+}
+
+func (b *Breadcrumb) SetOnSelected(f func(node *ASTNode)) {
+	b.onSelected = f
+}
+
+// SetPath replaces the path shown, outermost node first
+func (b *Breadcrumb) SetPath(path []*ASTNode) {
+	b.path = path
+}
+
+// visiblePath returns the crumbs that are shown, with nil standing for the
+// elided middle of a long path
+func (b *Breadcrumb) visiblePath() []*ASTNode {
+	if len(b.path) <= maxCrumbs {
+		return b.path
+	}
+	visible := []*ASTNode{b.path[0], nil}
+	return append(visible, b.path[len(b.path)-(maxCrumbs-1):]...)
+}
+
+func (b *Breadcrumb) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	path := b.visiblePath()
+	if len(b.crumbButtons) != len(path) {
+		b.crumbButtons = make([]basicwidget.Button, len(path))
+		b.separators = make([]basicwidget.Text, len(path))
+	}
+
+	for i, node := range path {
+		if i > 0 {
+			adder.AddChild(&b.separators[i])
+			b.separators[i].SetValue("›")
+		}
+		if node == nil {
+			adder.AddChild(&b.elisionText)
+			b.elisionText.SetValue("…")
+			continue
+		}
+		adder.AddChild(&b.crumbButtons[i])
+		b.crumbButtons[i].SetText(crumbLabel(node))
+		b.crumbButtons[i].SetOnDown(func() {
+			if b.onSelected != nil {
+				b.onSelected(node)
+			}
+		})
+	}
+
+	return nil
+}
+
+// crumbLabel names a node in a few words, such as "FuncDecl main"
+func crumbLabel(node *ASTNode) string {
+	if node.Node == nil {
+		return strings.Replace(node.Label, ": ", " ", 1)
+	}
+	if _, ok := node.Node.(*ast.File); ok {
+		return strings.Replace(node.Label, ": ", " ", 1)
+	}
+	return nodeSummary(node.Node)
+}
+
+func (b *Breadcrumb) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+
+	path := b.visiblePath()
+	var items []guigui.LinearLayoutItem
+	for i, node := range path {
+		if i > 0 {
+			items = append(items, guigui.LinearLayoutItem{
+				Widget: &b.separators[i],
+			})
+		}
+		if node == nil {
+			items = append(items, guigui.LinearLayoutItem{
+				Widget: &b.elisionText,
+			})
+			continue
+		}
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &b.crumbButtons[i],
+		})
+	}
+
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items:     items,
+		Gap:       u / 4,
+	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+}
+
+func (b *Breadcrumb) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	h := u
+	if len(b.crumbButtons) > 0 {
+		h = b.crumbButtons[0].Measure(context, guigui.Constraints{}).Y
+	}
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+	"strings"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// BuildContextBar selects the GOOS, GOARCH and build tags that build
+// constraints are evaluated for
+type BuildContextBar struct {
+	guigui.DefaultWidget
+
+	goosDropdown   basicwidget.DropdownList[string]
+	goarchDropdown basicwidget.DropdownList[string]
+	tagsInput      basicwidget.TextInput
+
+	context   BuildContext
+	onChanged func(BuildContext)
+}
+
+// SetOnChanged registers a callback receiving the build context whenever
+// it is changed
+func (b *BuildContextBar) SetOnChanged(f func(BuildContext)) {
+	b.onChanged = f
+}
+
+// SetBuildContext sets the build context shown by the bar
+func (b *BuildContextBar) SetBuildContext(bc BuildContext) {
+	b.context = bc
+	b.tagsInput.SetValue(strings.Join(bc.Tags, ","))
+}
+
+func (b *BuildContextBar) changed() {
+	if b.onChanged != nil {
+		b.onChanged(b.context)
+	}
+}
+
+// dropdownItems turns values into dropdown items labeled with a prefix
+func dropdownItems(prefix string, values []string) []basicwidget.DropdownListItem[string] {
+	items := make([]basicwidget.DropdownListItem[string], len(values))
+	for i, v := range values {
+		items[i] = basicwidget.DropdownListItem[string]{
+			Text:  prefix + v,
+			Value: v,
+		}
+	}
+	return items
+}
+
+func (b *BuildContextBar) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	adder.AddChild(&b.goosDropdown)
+	adder.AddChild(&b.goarchDropdown)
+	adder.AddChild(&b.tagsInput)
+
+	goosItems := dropdownItems("GOOS: ", goosList)
+	b.goosDropdown.SetItems(goosItems)
+	b.goosDropdown.SelectItemByValue(b.context.GOOS)
+	b.goosDropdown.SetOnItemSelected(func(index int) {
+		b.context.GOOS = goosItems[index].Value
+		b.changed()
+	})
+
+	goarchItems := dropdownItems("GOARCH: ", goarchList)
+	b.goarchDropdown.SetItems(goarchItems)
+	b.goarchDropdown.SelectItemByValue(b.context.GOARCH)
+	b.goarchDropdown.SetOnItemSelected(func(index int) {
+		b.context.GOARCH = goarchItems[index].Value
+		b.changed()
+	})
+
+	b.tagsInput.SetOnValueChanged(func(text string, committed bool) {
+		if !committed {
+			return
+		}
+		b.context.Tags = strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		b.changed()
+	})
+
+	return nil
+}
+
+func (b *BuildContextBar) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &b.goosDropdown,
+			},
+			{
+				Widget: &b.goarchDropdown,
+			},
+			{
+				Widget: &b.tagsInput,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 4,
+	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+}
+
+func (b *BuildContextBar) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	h := b.tagsInput.Measure(context, guigui.Constraints{}).Y
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
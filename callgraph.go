@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/ssa"
+)
+
+// CallGraph is the call graph of the packages of an archive, built with
+// class hierarchy analysis so that calls through interfaces are included
+type CallGraph struct {
+	Program *ssa.Program
+	Graph   *callgraph.Graph
+
+	// functions are the functions declared in the archive, in source order
+	functions []*callgraph.Node
+	// instances maps generic functions to the nodes of their instantiations
+	instances map[*ssa.Function][]*callgraph.Node
+}
+
+// BuildCallGraph builds SSA for the type-checked packages of the archive
+// and computes their call graph. The packages must be free of type errors.
+func BuildCallGraph(a *Archive, ti *TypeInfo) (*CallGraph, error) {
+	if len(ti.Errors) > 0 {
+		return nil, errors.New("the call graph needs the archive to type-check without errors")
+	}
+	// The fake C package has no real declarations to build SSA from
+	if usesCgo(a.Files) {
+		return nil, errors.New("the call graph is not available for packages using cgo")
+	}
+
+	prog := ssa.NewProgram(a.Fset, ssa.InstantiateGenerics)
+
+	// Imported packages only contribute their declarations
+	var createImports func(pkgs []*types.Package)
+	createImports = func(pkgs []*types.Package) {
+		for _, pkg := range pkgs {
+			if prog.ImportedPackage(pkg.Path()) == nil {
+				prog.CreatePackage(pkg, nil, nil, true)
+				createImports(pkg.Imports())
+			}
+		}
+	}
+	for _, pkg := range ti.Packages {
+		createImports(pkg.Imports())
+	}
+
+	var ssaPkgs []*ssa.Package
+	for i, files := range a.packageFiles() {
+		ssaPkgs = append(ssaPkgs, prog.CreatePackage(ti.Packages[i], files, ti.Info, false))
+	}
+	prog.Build()
+
+	cg := &CallGraph{
+		Program:   prog,
+		Graph:     cha.CallGraph(prog),
+		instances: make(map[*ssa.Function][]*callgraph.Node),
+	}
+	for _, node := range cg.Graph.Nodes {
+		if node.Func == nil {
+			continue
+		}
+		if origin := node.Func.Origin(); origin != nil {
+			cg.instances[origin] = append(cg.instances[origin], node)
+			continue
+		}
+		if node.Func.Pos().IsValid() && slices.Contains(ssaPkgs, node.Func.Pkg) {
+			cg.functions = append(cg.functions, node)
+		}
+	}
+	// A generic method such as (*List[T]).Push is only reachable through
+	// its instances, so its origin has no node of its own yet
+	for origin := range cg.instances {
+		if _, ok := cg.Graph.Nodes[origin]; ok {
+			continue
+		}
+		node := cg.Graph.CreateNode(origin)
+		if origin.Pos().IsValid() && slices.Contains(ssaPkgs, origin.Pkg) {
+			cg.functions = append(cg.functions, node)
+		}
+	}
+	slices.SortFunc(cg.functions, func(x, y *callgraph.Node) int {
+		return cmp.Compare(x.Func.Pos(), y.Func.Pos())
+	})
+	return cg, nil
+}
+
+// nodeFor returns the call graph node of the function a FuncDecl declares
+func (cg *CallGraph) nodeFor(ti *TypeInfo, decl *ast.FuncDecl) *callgraph.Node {
+	obj, ok := ti.Info.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	fn := cg.Program.FuncValue(obj)
+	if fn == nil {
+		return nil
+	}
+	return cg.Graph.Nodes[fn]
+}
+
+// callers returns the edges into a function, including those into its
+// instantiations if it is generic
+func (cg *CallGraph) callers(node *callgraph.Node) []*callgraph.Edge {
+	edges := slices.Clone(node.In)
+	for _, inst := range cg.instances[node.Func] {
+		edges = append(edges, inst.In...)
+	}
+	return edges
+}
+
+// callees returns the edges out of a function, including those out of its
+// instantiations if it is generic
+func (cg *CallGraph) callees(node *callgraph.Node) []*callgraph.Edge {
+	edges := slices.Clone(node.Out)
+	for _, inst := range cg.instances[node.Func] {
+		edges = append(edges, inst.Out...)
+	}
+	return edges
+}
+
+// callSiteEdges returns the edges of the calls made at a call expression
+func (cg *CallGraph) callSiteEdges(call *ast.CallExpr) []*callgraph.Edge {
+	var edges []*callgraph.Edge
+	for _, node := range cg.functions {
+		for _, e := range cg.callees(node) {
+			if e.Site != nil && e.Site.Common().Pos() == call.Lparen {
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+// CallGraphNodes shows the callers and callees of the selected function
+// declaration or call expression, followed by every function of the archive
+// with the functions it calls
+func CallGraphNodes(a *Archive, ti *TypeInfo, cg *CallGraph, selected ast.Node) []*ASTNode {
+	var nodes []*ASTNode
+
+	switch n := selected.(type) {
+	case *ast.FuncDecl:
+		if node := cg.nodeFor(ti, n); node != nil {
+			nodes = append(nodes, &ASTNode{
+				Label:       "Selected: " + node.Func.String(),
+				IndentLevel: 1,
+				Node:        n,
+				Children: []*ASTNode{
+					edgesToNode(a, "Callers", cg.callers(node), true, 2),
+					edgesToNode(a, "Callees", cg.callees(node), false, 2),
+				},
+			})
+		}
+	case *ast.CallExpr:
+		nodes = append(nodes, &ASTNode{
+			Label:       "Selected: call to " + exprToString(n.Fun),
+			IndentLevel: 1,
+			Node:        n,
+			Children: []*ASTNode{
+				edgesToNode(a, "Callees", cg.callSiteEdges(n), false, 2),
+			},
+		})
+	default:
+		nodes = append(nodes, &ASTNode{
+			Label:       "Select a function declaration or call in the AST to see its callers and callees",
+			IndentLevel: 1,
+		})
+	}
+
+	functionsNode := &ASTNode{
+		Label:       fmt.Sprintf("Functions (%d)", len(cg.functions)),
+		IndentLevel: 1,
+	}
+	for _, node := range cg.functions {
+		callees := cg.callees(node)
+		fnNode := edgesToNode(a, node.Func.String(), callees, false, 2)
+		fnNode.Label = fmt.Sprintf("%s (calls %d, called by %d)", node.Func.String(), len(callees), len(cg.callers(node)))
+		fnNode.Node = a.displayedNodeAt(node.Func.Pos())
+		fnNode.Collapsed = len(fnNode.Children) > 0
+		functionsNode.Children = append(functionsNode.Children, fnNode)
+	}
+	nodes = append(nodes, functionsNode)
+
+	LinkParents(nodes, nil)
+	return nodes
+}
+
+// edgesToNode lists call edges, naming the caller or the callee, each
+// linked to its call site
+func edgesToNode(a *Archive, title string, edges []*callgraph.Edge, callers bool, level int) *ASTNode {
+	node := &ASTNode{
+		Label:       fmt.Sprintf("%s (%d)", title, len(edges)),
+		IndentLevel: level,
+	}
+	for _, e := range edges {
+		fn := e.Callee.Func
+		if callers {
+			fn = e.Caller.Func
+		}
+		label := fn.String()
+		var site ast.Node
+		if e.Site != nil {
+			pos := e.Site.Common().Pos()
+			if pos.IsValid() {
+				label += " at " + positionString(a, pos)
+				site = a.displayedNodeAt(pos)
+			}
+		}
+		node.Children = append(node.Children, &ASTNode{
+			Label:       label,
+			IndentLevel: level + 1,
+			Node:        site,
+		})
+	}
+	return node
+}
+
+// DOT renders the whole call graph in Graphviz DOT format, with one edge per
+// caller and callee pair
+func (cg *CallGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph callgraph {\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	seen := make(map[[2]*ssa.Function]bool)
+	for _, node := range cg.functions {
+		for _, e := range cg.callees(node) {
+			key := [2]*ssa.Function{e.Caller.Func, e.Callee.Func}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(e.Caller.Func.String()), strconv.Quote(e.Callee.Func.String()))
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
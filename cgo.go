@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cgoHelpers are the functions cgo provides in the C package itself
+var cgoHelpers = []string{"CString", "CBytes", "GoString", "GoStringN", "GoBytes"}
+
+// cgoNumericTypes are the C numeric types cgo maps to Go types
+var cgoNumericTypes = []string{
+	"char", "schar", "uchar", "short", "ushort", "int", "uint", "long", "ulong",
+	"longlong", "ulonglong", "float", "double", "complexfloat", "complexdouble",
+	"size_t",
+}
+
+// cgoImport returns the import "C" spec of a file and the preamble comment
+// cgo reads C declarations from, or nil if the file does not use cgo
+func cgoImport(f *ast.File) (*ast.ImportSpec, *ast.CommentGroup) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			is := spec.(*ast.ImportSpec)
+			if path, _ := strconv.Unquote(is.Path.Value); path != "C" {
+				continue
+			}
+			doc := is.Doc
+			if doc == nil && !gd.Lparen.IsValid() {
+				doc = gd.Doc
+			}
+			return is, doc
+		}
+	}
+	return nil, nil
+}
+
+// usesCgo reports whether any of the files imports "C"
+func usesCgo(files []*ast.File) bool {
+	return slices.ContainsFunc(files, func(f *ast.File) bool {
+		is, _ := cgoImport(f)
+		return is != nil
+	})
+}
+
+// cgoToNode shows the cgo structure of a file: the preamble with its #cgo
+// flags, the C names the Go code refers to and the functions exported to C
+func cgoToNode(fset *token.FileSet, f *ast.File, level int) *ASTNode {
+	is, preamble := cgoImport(f)
+	if is == nil {
+		return nil
+	}
+
+	node := &ASTNode{
+		Label:       "cgo",
+		IndentLevel: level,
+		Node:        is,
+		Children: []*ASTNode{{
+			Label:       `Note: import "C" parses as an ordinary import; cgo generates package C from the preamble`,
+			IndentLevel: level + 1,
+		}},
+	}
+	node.Children = append(node.Children, preambleToNode(fset, preamble, level+1))
+
+	refsNode := cgoRefsToNode(fset, f, level+1)
+	node.Children = append(node.Children, refsNode)
+	node.Label = fmt.Sprintf("cgo (%d C references)", len(refsNode.Children))
+
+	var exported []*ASTNode
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Doc == nil {
+			continue
+		}
+		for _, c := range fd.Doc.List {
+			if name, ok := strings.CutPrefix(c.Text, "//export "); ok {
+				exported = append(exported, &ASTNode{
+					Label:       fmt.Sprintf("%s (Go function %s)", strings.TrimSpace(name), fd.Name.Name),
+					IndentLevel: level + 2,
+					Node:        fd,
+				})
+			}
+		}
+	}
+	node.Children = append(node.Children, &ASTNode{
+		Label:       fmt.Sprintf("Exported to C (%d)", len(exported)),
+		IndentLevel: level + 1,
+		Children:    exported,
+	})
+	return node
+}
+
+// preambleToNode lists the lines of the cgo preamble, with #cgo lines
+// broken down into their options
+func preambleToNode(fset *token.FileSet, preamble *ast.CommentGroup, level int) *ASTNode {
+	if preamble == nil {
+		return &ASTNode{
+			Label:       "Preamble: (none)",
+			IndentLevel: level,
+		}
+	}
+	pos := fset.Position(preamble.Pos())
+	lines := splitLines(strings.TrimRight(preamble.Text(), "\n"))
+	node := &ASTNode{
+		Label:       fmt.Sprintf("Preamble %d:%d (%d lines)", pos.Line, pos.Column, len(lines)),
+		IndentLevel: level,
+		Node:        preamble,
+	}
+	for _, line := range lines {
+		lineNode := &ASTNode{
+			Label:       "C: " + line,
+			IndentLevel: level + 1,
+		}
+		if directive, ok := strings.CutPrefix(strings.TrimSpace(line), "#cgo "); ok {
+			lineNode.Label = "#cgo: " + directive
+			opts, values, ok := strings.Cut(directive, ":")
+			fields := strings.Fields(opts)
+			if !ok || len(fields) == 0 {
+				// cgo rejects a directive without a colon or a variable name
+				lineNode.Label = "#cgo (malformed): " + directive
+				lineNode.Color = testFailColor
+			} else {
+				lineNode.Children = append(lineNode.Children, &ASTNode{
+					Label:       "Variable: " + fields[len(fields)-1],
+					IndentLevel: level + 2,
+				})
+				if len(fields) > 1 {
+					lineNode.Children = append(lineNode.Children, &ASTNode{
+						Label:       "Constraint: " + strings.Join(fields[:len(fields)-1], " "),
+						IndentLevel: level + 2,
+					})
+				}
+				lineNode.Children = append(lineNode.Children, &ASTNode{
+					Label:       "Values: " + strings.TrimSpace(values),
+					IndentLevel: level + 2,
+				})
+			}
+		}
+		node.Children = append(node.Children, lineNode)
+	}
+	return node
+}
+
+// cgoRefsToNode lists the C.xxx names used by a file, grouped by what cgo
+// will turn them into, each with the places it is used
+func cgoRefsToNode(fset *token.FileSet, f *ast.File, level int) *ASTNode {
+	uses := make(map[string][]*ast.SelectorExpr)
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == "C" {
+			uses[sel.Sel.Name] = append(uses[sel.Sel.Name], sel)
+		}
+		return true
+	})
+
+	names := make([]string, 0, len(uses))
+	for name := range uses {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(x, y string) int {
+		return cmp.Or(
+			cmp.Compare(cgoRefKind(x), cgoRefKind(y)),
+			cmp.Compare(x, y),
+		)
+	})
+
+	node := &ASTNode{
+		Label:       fmt.Sprintf("C references (%d)", len(names)),
+		IndentLevel: level,
+	}
+	for _, name := range names {
+		refNode := &ASTNode{
+			Label:       fmt.Sprintf("C.%s: %s (%d uses)", name, cgoRefKind(name), len(uses[name])),
+			IndentLevel: level + 1,
+			Collapsed:   true,
+		}
+		for _, sel := range uses[name] {
+			pos := fset.Position(sel.Pos())
+			refNode.Children = append(refNode.Children, &ASTNode{
+				Label:       fmt.Sprintf("%d:%d", pos.Line, pos.Column),
+				IndentLevel: level + 2,
+				Node:        sel,
+			})
+		}
+		node.Children = append(node.Children, refNode)
+	}
+	return node
+}
+
+// cgoRefKind classifies a name of the C package by its form
+func cgoRefKind(name string) string {
+	switch {
+	case slices.Contains(cgoHelpers, name):
+		return "cgo helper"
+	case slices.Contains(cgoNumericTypes, name):
+		return "numeric type"
+	case strings.HasPrefix(name, "struct_"), strings.HasPrefix(name, "union_"), strings.HasPrefix(name, "enum_"):
+		return "tagged type"
+	case strings.HasPrefix(name, "sizeof_"):
+		return "size constant"
+	}
+	return "function, variable, macro or typedef"
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+	"testing"
+)
+
+func TestPreambleToNode(t *testing.T) {
+	tests := []struct {
+		line     string
+		label    string
+		children []string
+	}{
+		{
+			line:     "#cgo LDFLAGS: -lm",
+			label:    "#cgo: LDFLAGS: -lm",
+			children: []string{"Variable: LDFLAGS", "Values: -lm"},
+		},
+		{
+			line:     "#cgo linux,amd64 CFLAGS: -O2 -g",
+			label:    "#cgo: linux,amd64 CFLAGS: -O2 -g",
+			children: []string{"Variable: CFLAGS", "Constraint: linux,amd64", "Values: -O2 -g"},
+		},
+		{
+			line:  "#cgo : -lm",
+			label: "#cgo (malformed): : -lm",
+		},
+		{
+			line:  "#cgo LDFLAGS -lm",
+			label: "#cgo (malformed): LDFLAGS -lm",
+		},
+		{
+			line:  "#include <math.h>",
+			label: "C: #include <math.h>",
+		},
+	}
+
+	for _, tt := range tests {
+		preamble := &ast.CommentGroup{List: []*ast.Comment{{Text: "// " + tt.line}}}
+		node := preambleToNode(token.NewFileSet(), preamble, 1)
+		if len(node.Children) != 1 {
+			t.Fatalf("%q: got %d lines, want 1", tt.line, len(node.Children))
+		}
+		lineNode := node.Children[0]
+		if lineNode.Label != tt.label {
+			t.Errorf("%q: label = %q, want %q", tt.line, lineNode.Label, tt.label)
+		}
+		var children []string
+		for _, child := range lineNode.Children {
+			children = append(children, child.Label)
+		}
+		if !slices.Equal(children, tt.children) {
+			t.Errorf("%q: children = %q, want %q", tt.line, children, tt.children)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/doc/comment"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// commentsToNode lists every comment group of the file together with the
+// nodes that ast.NewCommentMap associates it with
+func commentsToNode(fset *token.FileSet, f *ast.File, level int) *ASTNode {
+	if len(f.Comments) == 0 {
+		return nil
+	}
+
+	// Invert the comment map so that each group knows its owners
+	owners := make(map[*ast.CommentGroup][]ast.Node)
+	for n, groups := range ast.NewCommentMap(fset, f, f.Comments) {
+		for _, cg := range groups {
+			owners[cg] = append(owners[cg], n)
+		}
+	}
+
+	node := &ASTNode{
+		Label:       fmt.Sprintf("Comments (%d)", len(f.Comments)),
+		IndentLevel: level,
+	}
+	for _, cg := range f.Comments {
+		pos := fset.Position(cg.Pos())
+		groupNode := &ASTNode{
+			Label:       fmt.Sprintf("CommentGroup %d:%d: %s", pos.Line, pos.Column, commentSummary(cg)),
+			IndentLevel: level + 1,
+		}
+
+		nodes := owners[cg]
+		slices.SortFunc(nodes, func(a, b ast.Node) int {
+			return cmp.Compare(a.Pos(), b.Pos())
+		})
+		for _, n := range nodes {
+			groupNode.Children = append(groupNode.Children, &ASTNode{
+				Label:       fmt.Sprintf("Attached to: %s (line %d)", nodeSummary(n), fset.Position(n.Pos()).Line),
+				IndentLevel: level + 2,
+			})
+		}
+		if len(nodes) == 0 {
+			groupNode.Children = append(groupNode.Children, &ASTNode{
+				Label:       "Attached to: (none)",
+				IndentLevel: level + 2,
+			})
+		}
+
+		for _, c := range cg.List {
+			groupNode.Children = append(groupNode.Children, &ASTNode{
+				Label:       fmt.Sprintf("Comment: %s", c.Text),
+				IndentLevel: level + 2,
+			})
+		}
+
+		node.Children = append(node.Children, groupNode)
+	}
+
+	return node
+}
+
+// commentSummary returns the first line of a comment group, shortened for display
+func commentSummary(cg *ast.CommentGroup) string {
+	text, _, _ := strings.Cut(strings.TrimSpace(cg.Text()), "\n")
+	if text == "" {
+		// Directives such as //go:build are dropped by Text
+		text = cg.List[0].Text
+	}
+	return elide(text, maxExprLen)
+}
+
+// nodeSummary describes an AST node in a few words
+func nodeSummary(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.File:
+		return fmt.Sprintf("File (package %s)", n.Name.Name)
+	case *ast.FuncDecl:
+		return fmt.Sprintf("FuncDecl %s", n.Name.Name)
+	case *ast.GenDecl:
+		return fmt.Sprintf("GenDecl %s", n.Tok.String())
+	case *ast.TypeSpec:
+		return fmt.Sprintf("TypeSpec %s", n.Name.Name)
+	case *ast.ValueSpec:
+		return fmt.Sprintf("ValueSpec %s", identNames(n.Names))
+	case *ast.ImportSpec:
+		return fmt.Sprintf("ImportSpec %s", n.Path.Value)
+	case *ast.Field:
+		if len(n.Names) == 0 {
+			return "Field (embedded)"
+		}
+		return fmt.Sprintf("Field %s", identNames(n.Names))
+	case *ast.Ident:
+		return fmt.Sprintf("Ident %s", n.Name)
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+}
+
+// identNames joins the names of a list of identifiers
+func identNames(idents []*ast.Ident) string {
+	names := make([]string, len(idents))
+	for i, id := range idents {
+		names[i] = id.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// docToNode renders a doc comment as its go/doc/comment structure
+func docToNode(cg *ast.CommentGroup, level int) *ASTNode {
+	if cg == nil {
+		return nil
+	}
+	return docTextToNode(&comment.Parser{}, cg.Text(), level)
+}
+
+// docTextToNode parses doc comment text with p and renders its structure
+func docTextToNode(p *comment.Parser, text string, level int) *ASTNode {
+	doc := p.Parse(text)
+
+	node := &ASTNode{
+		Label:       "Doc",
+		IndentLevel: level,
+	}
+	node.Children = blocksToNodes(doc.Content, level+1)
+	for _, def := range doc.Links {
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("LinkDef: [%s]: %s", def.Text, def.URL),
+			IndentLevel: level + 1,
+		})
+	}
+
+	return node
+}
+
+// blocksToNodes converts doc comment blocks to display nodes
+func blocksToNodes(blocks []comment.Block, level int) []*ASTNode {
+	var nodes []*ASTNode
+
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *comment.Heading:
+			nodes = append(nodes, &ASTNode{
+				Label:       fmt.Sprintf("Heading: %s", docText(b.Text)),
+				IndentLevel: level,
+			})
+
+		case *comment.Paragraph:
+			paraNode := &ASTNode{
+				Label:       fmt.Sprintf("Paragraph: %s", docText(b.Text)),
+				IndentLevel: level,
+			}
+			paraNode.Children = linksToNodes(b.Text, level+1)
+			nodes = append(nodes, paraNode)
+
+		case *comment.List:
+			listNode := &ASTNode{
+				Label:       "List",
+				IndentLevel: level,
+			}
+			for i, item := range b.Items {
+				marker := item.Number
+				if marker == "" {
+					marker = "-"
+				}
+				itemNode := &ASTNode{
+					Label:       fmt.Sprintf("Item %d (%s)", i+1, marker),
+					IndentLevel: level + 1,
+				}
+				itemNode.Children = blocksToNodes(item.Content, level+2)
+				listNode.Children = append(listNode.Children, itemNode)
+			}
+			nodes = append(nodes, listNode)
+
+		case *comment.Code:
+			codeNode := &ASTNode{
+				Label:       "Code",
+				IndentLevel: level,
+			}
+			for line := range strings.Lines(b.Text) {
+				codeNode.Children = append(codeNode.Children, &ASTNode{
+					Label:       strings.TrimRight(line, "\n"),
+					IndentLevel: level + 1,
+				})
+			}
+			nodes = append(nodes, codeNode)
+		}
+	}
+
+	return nodes
+}
+
+// linksToNodes lists the links and doc links contained in a run of text
+func linksToNodes(texts []comment.Text, level int) []*ASTNode {
+	var nodes []*ASTNode
+
+	for _, t := range texts {
+		switch t := t.(type) {
+		case *comment.Link:
+			nodes = append(nodes, &ASTNode{
+				Label:       fmt.Sprintf("Link: %s -> %s", docText(t.Text), t.URL),
+				IndentLevel: level,
+			})
+		case *comment.DocLink:
+			nodes = append(nodes, &ASTNode{
+				Label:       fmt.Sprintf("DocLink: %s -> %s", docText(t.Text), t.DefaultURL("https://pkg.go.dev")),
+				IndentLevel: level,
+			})
+		}
+	}
+
+	return nodes
+}
+
+// docText flattens doc comment text to a plain string
+func docText(texts []comment.Text) string {
+	var sb strings.Builder
+	for _, t := range texts {
+		switch t := t.(type) {
+		case comment.Plain:
+			sb.WriteString(string(t))
+		case comment.Italic:
+			sb.WriteString(string(t))
+		case *comment.Link:
+			sb.WriteString(docText(t.Text))
+		case *comment.DocLink:
+			sb.WriteString(docText(t.Text))
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"go/token"
+	"image/color"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compilerTimeout bounds how long a build of the archive may take
+const compilerTimeout = time.Minute
+
+var compilerNoteColor = color.RGBA{R: 0x57, G: 0x60, B: 0x6a, A: 0xff}
+
+// compilerNotes are the optimization messages of -gcflags=-m=2 that are kept;
+// the explanations that follow them are dropped
+var compilerNotes = []string{
+	"escapes to heap",
+	"moved to heap",
+	"does not escape",
+	"leaking param",
+	"can inline",
+	"cannot inline",
+	"inlining call to",
+}
+
+var diagnosticLine = regexp.MustCompile(`^(.+\.go):(\d+):(\d+): (.*)$`)
+
+// Diagnostic is a message the compiler reported at a position
+type Diagnostic struct {
+	Filename string
+	Line     int
+	Column   int
+	Message  string
+}
+
+// CompilerDiagnostics builds the archive in a temporary module with
+// -gcflags=-m=2 and returns the escape analysis and inlining decisions
+func CompilerDiagnostics(a *Archive) ([]Diagnostic, error) {
+	w, err := a.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), compilerTimeout)
+	defer cancel()
+
+	out, err := w.Command(ctx, "build", "-gcflags=-m=2", "./...").CombinedOutput()
+	diags := parseDiagnostics(out)
+	if err != nil && len(diags) == 0 {
+		return nil, fmt.Errorf("go build: %w\n%s", err, out)
+	}
+	slices.SortStableFunc(diags, func(x, y Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(x.Filename, y.Filename),
+			cmp.Compare(x.Line, y.Line),
+			cmp.Compare(x.Column, y.Column),
+		)
+	})
+	return diags, nil
+}
+
+// parseDiagnostics extracts the kept compiler notes from build output
+func parseDiagnostics(out []byte) []Diagnostic {
+	var diags []Diagnostic
+	seen := make(map[Diagnostic]bool)
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		d, ok := parseDiagnosticLine(sc.Text())
+		if !ok || strings.HasPrefix(d.Message, " ") || !isCompilerNote(d.Message) {
+			continue
+		}
+		// Inlining notes end with the body of the function
+		msg, _, _ := strings.Cut(d.Message, " as: ")
+		d.Message = strings.TrimSuffix(msg, ":")
+		if !seen[d] {
+			seen[d] = true
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// parseDiagnosticLine parses a line of the form file.go:line:column: message
+func parseDiagnosticLine(s string) (Diagnostic, bool) {
+	m := diagnosticLine.FindStringSubmatch(s)
+	if m == nil {
+		return Diagnostic{}, false
+	}
+	line, _ := strconv.Atoi(m[2])
+	col, _ := strconv.Atoi(m[3])
+	return Diagnostic{
+		Filename: path.Clean(strings.TrimPrefix(m[1], "./")),
+		Line:     line,
+		Column:   col,
+		Message:  m[4],
+	}, true
+}
+
+func isCompilerNote(msg string) bool {
+	for _, note := range compilerNotes {
+		if strings.Contains(msg, note) {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnosticPos converts the position of a diagnostic into a position in
+// the archive, or token.NoPos if its file is not part of it
+func (a *Archive) diagnosticPos(d Diagnostic) token.Pos {
+	for _, f := range a.Files {
+		tf := a.Fset.File(f.Pos())
+		if tf.Name() != d.Filename || d.Line < 1 || d.Line > tf.LineCount() {
+			continue
+		}
+		start := tf.LineStart(d.Line)
+		offset := tf.Offset(start) + d.Column - 1
+		if offset > tf.Size() {
+			return start
+		}
+		return tf.Pos(offset)
+	}
+	return token.NoPos
+}
+
+// AnnotateDiagnostics attaches each compiler note to the innermost node at
+// its position
+func AnnotateDiagnostics(a *Archive, diags []Diagnostic) {
+	for _, d := range diags {
+		pos := a.diagnosticPos(d)
+		if !pos.IsValid() {
+			continue
+		}
+		n := a.displayedNodeAt(pos)
+		if n == nil {
+			continue
+		}
+		path := FindPath(a.Nodes, n)
+		node := path[len(path)-1]
+		node.Children = append(node.Children, &ASTNode{
+			Label:       "Compiler: " + d.Message,
+			IndentLevel: node.IndentLevel + 1,
+			Node:        n,
+			Color:       compilerNoteColor,
+			Parent:      node,
+			Annotation:  true,
+		})
+	}
+}
+
+// DiagnosticNodes lists the compiler notes by file and line, each linked to
+// the node it is attached to
+func DiagnosticNodes(a *Archive, diags []Diagnostic) []*ASTNode {
+	var nodes []*ASTNode
+	files := make(map[string]*ASTNode)
+	for _, d := range diags {
+		fileNode, ok := files[d.Filename]
+		if !ok {
+			fileNode = &ASTNode{
+				IndentLevel: 1,
+			}
+			files[d.Filename] = fileNode
+			nodes = append(nodes, fileNode)
+		}
+		fileNode.Children = append(fileNode.Children, &ASTNode{
+			Label:       fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message),
+			IndentLevel: 2,
+			Node:        a.displayedNodeAt(a.diagnosticPos(d)),
+		})
+		fileNode.Label = fmt.Sprintf("File: %s (%d notes)", d.Filename, len(fileNode.Children))
+	}
+	if len(nodes) == 0 {
+		nodes = append(nodes, &ASTNode{
+			Label:       "The compiler reported no escape analysis or inlining notes",
+			IndentLevel: 1,
+		})
+	}
+	LinkParents(nodes, nil)
+	return nodes
+}
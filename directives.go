@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/token"
+	"image/color"
+	"io"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// directiveLine matches the comments the go/ast package treats as
+// directives rather than doc comment text
+var directiveLine = regexp.MustCompile(`^//(line |extern |export |[a-z0-9]+:[a-z0-9])`)
+
+var excludedFileColor = color.RGBA{R: 0x8c, G: 0x95, B: 0x9f, A: 0xff}
+
+// goosList and goarchList are the targets offered by the build context
+// selector, most common first
+var (
+	goosList = []string{
+		"linux", "darwin", "windows", "freebsd", "openbsd", "netbsd", "dragonfly",
+		"android", "ios", "js", "wasip1", "plan9", "solaris", "illumos", "aix",
+	}
+	goarchList = []string{
+		"amd64", "arm64", "386", "arm", "wasm", "riscv64", "loong64",
+		"ppc64", "ppc64le", "mips", "mipsle", "mips64", "mips64le", "s390x",
+	}
+)
+
+// directivesToNode lists the directives of a file, such as //go:build and
+// //go:generate, with build constraints parsed into expression trees
+func directivesToNode(fset *token.FileSet, f *ast.File, level int) *ASTNode {
+	// Directives in doc comments apply to the declaration
+	targets := make(map[*ast.Comment]ast.Node)
+	for _, decl := range f.Decls {
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		if doc != nil {
+			for _, c := range doc.List {
+				targets[c] = decl
+			}
+		}
+	}
+
+	node := &ASTNode{
+		IndentLevel: level,
+	}
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if !directiveLine.MatchString(c.Text) && !constraint.IsPlusBuild(c.Text) {
+				continue
+			}
+			pos := fset.Position(c.Pos())
+			dNode := &ASTNode{
+				Label:       fmt.Sprintf("Directive %d:%d: %s", pos.Line, pos.Column, c.Text),
+				IndentLevel: level + 1,
+				Node:        c,
+			}
+			dNode.Children = directiveArgsToNodes(c.Text, level+2)
+			if target, ok := targets[c]; ok {
+				dNode.Children = append(dNode.Children, &ASTNode{
+					Label:       "Applies to: " + nodeSummary(target),
+					IndentLevel: level + 2,
+					Node:        target,
+				})
+			}
+			node.Children = append(node.Children, dNode)
+		}
+	}
+	if len(node.Children) == 0 {
+		return nil
+	}
+	node.Label = fmt.Sprintf("Directives (%d)", len(node.Children))
+	return node
+}
+
+// directiveArgsToNodes explains the arguments of the directives that take
+// structured ones
+func directiveArgsToNodes(text string, level int) []*ASTNode {
+	if constraint.IsGoBuild(text) || constraint.IsPlusBuild(text) {
+		expr, err := constraint.Parse(text)
+		if err != nil {
+			return []*ASTNode{{
+				Label:       "Error: " + err.Error(),
+				IndentLevel: level,
+			}}
+		}
+		return []*ASTNode{buildExprToNode(expr, level)}
+	}
+
+	// The name ends at the first space or tab
+	name, args := strings.TrimPrefix(text, "//"), ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, args = name[:i], name[i+1:]
+	}
+	fields := strings.Fields(args)
+	var labels []string
+	switch name {
+	case "go:generate":
+		labels = append(labels, "Command: "+strings.TrimSpace(args))
+	case "go:linkname":
+		if len(fields) > 0 {
+			labels = append(labels, "Local: "+fields[0])
+		}
+		if len(fields) > 1 {
+			labels = append(labels, "Target: "+fields[1])
+		}
+	case "go:embed":
+		patterns, err := parseEmbedPatterns(args)
+		for _, p := range patterns {
+			labels = append(labels, "Pattern: "+p)
+		}
+		if err != nil {
+			labels = append(labels, "Error: "+err.Error())
+		}
+	case "line":
+		labels = append(labels, "Position: "+strings.TrimSpace(args))
+	}
+
+	var nodes []*ASTNode
+	for _, label := range labels {
+		nodes = append(nodes, &ASTNode{
+			Label:       label,
+			IndentLevel: level,
+		})
+	}
+	return nodes
+}
+
+// buildExprToNode converts a build constraint expression to display nodes
+func buildExprToNode(expr constraint.Expr, level int) *ASTNode {
+	switch e := expr.(type) {
+	case *constraint.AndExpr:
+		return &ASTNode{
+			Label:       "And: " + e.String(),
+			IndentLevel: level,
+			Children: []*ASTNode{
+				buildExprToNode(e.X, level+1),
+				buildExprToNode(e.Y, level+1),
+			},
+		}
+	case *constraint.OrExpr:
+		return &ASTNode{
+			Label:       "Or: " + e.String(),
+			IndentLevel: level,
+			Children: []*ASTNode{
+				buildExprToNode(e.X, level+1),
+				buildExprToNode(e.Y, level+1),
+			},
+		}
+	case *constraint.NotExpr:
+		return &ASTNode{
+			Label:       "Not: " + e.String(),
+			IndentLevel: level,
+			Children: []*ASTNode{
+				buildExprToNode(e.X, level+1),
+			},
+		}
+	case *constraint.TagExpr:
+		return &ASTNode{
+			Label:       "Tag: " + e.Tag,
+			IndentLevel: level,
+		}
+	}
+	return &ASTNode{
+		Label:       fmt.Sprintf("%T", expr),
+		IndentLevel: level,
+	}
+}
+
+// BuildContext is the target the build constraints of the archive are
+// evaluated for
+type BuildContext struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// defaultBuildContext returns the build context of the host
+func defaultBuildContext() BuildContext {
+	return BuildContext{
+		GOOS:   runtime.GOOS,
+		GOARCH: runtime.GOARCH,
+	}
+}
+
+func (bc BuildContext) String() string {
+	s := bc.GOOS + "/" + bc.GOARCH
+	if len(bc.Tags) > 0 {
+		s += " with tags " + strings.Join(bc.Tags, ",")
+	}
+	return s
+}
+
+// ExcludedFiles returns the Go files of the archive that the go command
+// would not build for bc, because of their //go:build lines or their
+// _GOOS and _GOARCH name suffixes
+func (a *Archive) ExcludedFiles(bc BuildContext) map[*ast.File]bool {
+	ctxt := build.Default
+	ctxt.GOOS = bc.GOOS
+	ctxt.GOARCH = bc.GOARCH
+	ctxt.BuildTags = bc.Tags
+	// The go command disables cgo when cross-compiling unless a C compiler
+	// for the target is configured
+	ctxt.CgoEnabled = build.Default.CgoEnabled && bc.GOOS == runtime.GOOS && bc.GOARCH == runtime.GOARCH
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(archiveFileData(a.Txtar, name))), nil
+	}
+
+	excluded := make(map[*ast.File]bool)
+	for _, f := range a.Files {
+		name := a.Fset.File(f.Pos()).Name()
+		ok, err := ctxt.MatchFile(path.Dir(name), path.Base(name))
+		if err == nil && !ok {
+			excluded[f] = true
+		}
+	}
+	return excluded
+}
+
+// MarkExcludedFiles marks the file nodes of the tree that are excluded for
+// bc. It does nothing without an archive.
+func MarkExcludedFiles(a *Archive, bc BuildContext) {
+	if a == nil {
+		return
+	}
+	excluded := a.ExcludedFiles(bc)
+	for _, node := range a.Nodes {
+		if f, ok := node.Node.(*ast.File); ok {
+			node.Excluded = excluded[f]
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/build"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestExcludedFiles(t *testing.T) {
+	a, err := ParseTxtar(`-- a_linux.go --
+package a
+-- b_windows_arm64.go --
+package a
+-- c.go --
+//go:build cgo
+
+package a
+-- d.go --
+//go:build extra
+
+package a
+-- e.go --
+package a
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cgo is only enabled for the host, as with the go command
+	hostCgo := build.Default.CgoEnabled
+	tests := []struct {
+		bc   BuildContext
+		want []string
+	}{
+		{
+			bc:   BuildContext{GOOS: "linux", GOARCH: "amd64", Tags: []string{"extra"}},
+			want: []string{"b_windows_arm64.go"},
+		},
+		{
+			bc:   BuildContext{GOOS: "windows", GOARCH: "arm64"},
+			want: []string{"a_linux.go", "d.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		cgo := hostCgo && tt.bc.GOOS == runtime.GOOS && tt.bc.GOARCH == runtime.GOARCH
+		want := slices.Clone(tt.want)
+		if !cgo {
+			want = append(want, "c.go")
+		}
+		slices.Sort(want)
+
+		excluded := a.ExcludedFiles(tt.bc)
+		var got []string
+		for _, f := range a.Files {
+			if excluded[f] {
+				got = append(got, a.Fset.File(f.Pos()).Name())
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: excluded %q, want %q", tt.bc, got, want)
+		}
+	}
+}
+
+func TestDirectiveArgsToNodes(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "//go:embed a.txt b", want: []string{"Pattern: a.txt", "Pattern: b"}},
+		{text: "//go:embed\tfoo.txt", want: []string{"Pattern: foo.txt"}},
+		{text: "//go:linkname\ta b", want: []string{"Local: a", "Target: b"}},
+		{text: "//go:generate\tstringer -type=T", want: []string{"Command: stringer -type=T"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, node := range directiveArgsToNodes(tt.text, 1) {
+			got = append(got, node.Label)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMarkExcludedFilesWithoutArchive(t *testing.T) {
+	MarkExcludedFiles(nil, BuildContext{GOOS: "linux", GOARCH: "amd64"})
+}
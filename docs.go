@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/doc/comment"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// DocNodes builds the go/doc presentation of every package in the archive.
+// Entries point at the declarations they document so that the AST tree can
+// be revealed from them.
+func DocNodes(a *Archive) []*ASTNode {
+	// Group files by package; external test packages join the package they test
+	var names []string
+	pkgFiles := make(map[string][]*ast.File)
+	for _, f := range a.Files {
+		name := strings.TrimSuffix(f.Name.Name, "_test")
+		if _, ok := pkgFiles[name]; !ok {
+			names = append(names, name)
+		}
+		pkgFiles[name] = append(pkgFiles[name], f)
+	}
+
+	var nodes []*ASTNode
+	for _, name := range names {
+		files := pkgFiles[name]
+		// Without AllDecls go/doc strips unexported declarations from the
+		// files, which the other views share; they are left out below instead
+		p, err := doc.NewFromFiles(a.Fset, files, name, doc.AllDecls|doc.PreserveAST)
+		if err != nil {
+			nodes = append(nodes, &ASTNode{
+				Label:       fmt.Sprintf("Package %s (error: %v)", name, err),
+				IndentLevel: 1,
+			})
+			continue
+		}
+		nodes = append(nodes, packageDocToNode(p, files, 1))
+	}
+
+	return nodes
+}
+
+// packageDocToNode converts package documentation to a display node
+func packageDocToNode(p *doc.Package, files []*ast.File, level int) *ASTNode {
+	parser := p.Parser()
+
+	node := &ASTNode{
+		Label:       fmt.Sprintf("Package %s", p.Name),
+		IndentLevel: level,
+	}
+
+	if synopsis := p.Synopsis(p.Doc); synopsis != "" {
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("Synopsis: %s", synopsis),
+			IndentLevel: level + 1,
+		})
+	}
+	if p.Doc != "" {
+		node.Children = append(node.Children, docTextToNode(parser, p.Doc, level+1))
+	}
+
+	// The values and constructors of unexported types are listed at the
+	// package level, as go/doc does when it filters declarations itself
+	consts, vars, funcs := p.Consts, p.Vars, p.Funcs
+	var typeNodes []*ASTNode
+	for _, t := range p.Types {
+		if !token.IsExported(t.Name) {
+			consts = append(consts, t.Consts...)
+			vars = append(vars, t.Vars...)
+			funcs = append(funcs, t.Funcs...)
+			continue
+		}
+		typeNodes = append(typeNodes, typeDocToNode(parser, t, files, level+2))
+	}
+	slices.SortStableFunc(funcs, func(x, y *doc.Func) int {
+		return strings.Compare(x.Name, y.Name)
+	})
+
+	node.Children = appendSection(node.Children, "Constants", valuesToNodes(parser, consts, "Const", level+2), level+1)
+	node.Children = appendSection(node.Children, "Variables", valuesToNodes(parser, vars, "Var", level+2), level+1)
+	node.Children = appendSection(node.Children, "Functions", funcsToNodes(parser, funcs, files, level+2), level+1)
+	node.Children = appendSection(node.Children, "Types", typeNodes, level+1)
+
+	node.Children = appendSection(node.Children, "Examples", examplesToNodes(parser, p.Examples, files, level+2), level+1)
+
+	return node
+}
+
+// typeDocToNode converts type documentation to a display node
+func typeDocToNode(parser *comment.Parser, t *doc.Type, files []*ast.File, level int) *ASTNode {
+	node := &ASTNode{
+		Label:       fmt.Sprintf("Type: %s", t.Name),
+		IndentLevel: level,
+		Node:        t.Decl,
+	}
+	for _, spec := range t.Decl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == t.Name {
+			node.Node = ts
+		}
+	}
+
+	if t.Doc != "" {
+		node.Children = append(node.Children, docTextToNode(parser, t.Doc, level+1))
+	}
+	node.Children = appendSection(node.Children, "Constants", valuesToNodes(parser, t.Consts, "Const", level+2), level+1)
+	node.Children = appendSection(node.Children, "Variables", valuesToNodes(parser, t.Vars, "Var", level+2), level+1)
+	node.Children = appendSection(node.Children, "Functions", funcsToNodes(parser, t.Funcs, files, level+2), level+1)
+	node.Children = appendSection(node.Children, "Methods", funcsToNodes(parser, t.Methods, files, level+2), level+1)
+	node.Children = appendSection(node.Children, "Examples", examplesToNodes(parser, t.Examples, files, level+2), level+1)
+
+	return node
+}
+
+// valuesToNodes converts const or var documentation to display nodes
+func valuesToNodes(parser *comment.Parser, values []*doc.Value, kind string, level int) []*ASTNode {
+	var nodes []*ASTNode
+
+	for _, v := range values {
+		names := slices.DeleteFunc(slices.Clone(v.Names), func(name string) bool {
+			return !token.IsExported(name)
+		})
+		if len(names) == 0 {
+			continue
+		}
+		node := &ASTNode{
+			Label:       fmt.Sprintf("%s: %s", kind, strings.Join(names, ", ")),
+			IndentLevel: level,
+			Node:        v.Decl,
+		}
+		if v.Doc != "" {
+			node.Children = append(node.Children, docTextToNode(parser, v.Doc, level+1))
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// funcsToNodes converts func or method documentation to display nodes
+func funcsToNodes(parser *comment.Parser, funcs []*doc.Func, files []*ast.File, level int) []*ASTNode {
+	var nodes []*ASTNode
+
+	for _, f := range funcs {
+		if !token.IsExported(f.Name) {
+			continue
+		}
+		var label string
+		if f.Recv != "" {
+			label = fmt.Sprintf("Method: (%s) %s", f.Recv, f.Name)
+		} else {
+			label = fmt.Sprintf("Func: %s", f.Name)
+		}
+		node := &ASTNode{
+			Label:       label,
+			IndentLevel: level,
+			Node:        f.Decl,
+		}
+		if f.Doc != "" {
+			node.Children = append(node.Children, docTextToNode(parser, f.Doc, level+1))
+		}
+		node.Children = appendSection(node.Children, "Examples", examplesToNodes(parser, f.Examples, files, level+2), level+1)
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// examplesToNodes converts examples to display nodes
+func examplesToNodes(parser *comment.Parser, examples []*doc.Example, files []*ast.File, level int) []*ASTNode {
+	var nodes []*ASTNode
+
+	for _, ex := range examples {
+		name := ex.Name
+		if name == "" {
+			name = "(package)"
+		}
+		label := fmt.Sprintf("Example: %s", name)
+		if ex.Suffix != "" {
+			label = fmt.Sprintf("Example: %s (%s)", name, ex.Suffix)
+		}
+		node := &ASTNode{
+			Label:       label,
+			IndentLevel: level,
+			Node:        exampleDecl(ex, files),
+		}
+		if ex.Doc != "" {
+			node.Children = append(node.Children, docTextToNode(parser, ex.Doc, level+1))
+		}
+		switch {
+		case ex.Output != "":
+			outputNode := &ASTNode{
+				Label:       "Output",
+				IndentLevel: level + 1,
+			}
+			if ex.Unordered {
+				outputNode.Label = "Output (unordered)"
+			}
+			for line := range strings.Lines(ex.Output) {
+				outputNode.Children = append(outputNode.Children, &ASTNode{
+					Label:       strings.TrimRight(line, "\n"),
+					IndentLevel: level + 2,
+				})
+			}
+			node.Children = append(node.Children, outputNode)
+		case ex.EmptyOutput:
+			node.Children = append(node.Children, &ASTNode{
+				Label:       "Output: (empty)",
+				IndentLevel: level + 1,
+			})
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// exampleDecl finds the function declaration an example was extracted from
+func exampleDecl(ex *doc.Example, files []*ast.File) ast.Node {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body == ex.Code {
+				return fd
+			}
+		}
+	}
+	return nil
+}
+
+// appendSection appends a titled group of entries, skipping empty groups
+func appendSection(nodes []*ASTNode, title string, entries []*ASTNode, level int) []*ASTNode {
+	if len(entries) == 0 {
+		return nodes
+	}
+	return append(nodes, &ASTNode{
+		Label:       title,
+		Children:    entries,
+		IndentLevel: level,
+	})
+}
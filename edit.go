@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"reflect"
+	"slices"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/txtar"
+)
+
+// Rename changes the name of the identifier that n is or declares. Only
+// that identifier is changed; references elsewhere are left alone.
+func (a *Archive) Rename(n ast.Node, name string) error {
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("%q is not a valid identifier", name)
+	}
+
+	var ident *ast.Ident
+	switch n := n.(type) {
+	case *ast.Ident:
+		ident = n
+	case *ast.SelectorExpr:
+		ident = n.Sel
+	case *ast.FuncDecl:
+		ident = n.Name
+	case *ast.TypeSpec:
+		ident = n.Name
+	case *ast.Field:
+		if len(n.Names) == 1 {
+			ident = n.Names[0]
+		}
+	}
+	if ident == nil {
+		return fmt.Errorf("cannot rename %s", nodeSummary(n))
+	}
+
+	ident.Name = name
+	return nil
+}
+
+// SetOperator replaces the operator of a binary expression
+func (a *Archive) SetOperator(n ast.Node, op string) error {
+	expr, ok := n.(*ast.BinaryExpr)
+	if !ok {
+		return fmt.Errorf("cannot change the operator of %s", nodeSummary(n))
+	}
+
+	for tok := token.ADD; tok <= token.GEQ; tok++ {
+		if tok.String() == op && tok.Precedence() > token.LowestPrec {
+			expr.Op = tok
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a binary operator", op)
+}
+
+// Delete removes a statement, declaration or spec from its enclosing list
+func (a *Archive) Delete(n ast.Node) error {
+	switch n.(type) {
+	case ast.Stmt, ast.Decl, ast.Spec:
+	default:
+		return fmt.Errorf("cannot delete %s", nodeSummary(n))
+	}
+
+	var deleted bool
+	for i, f := range a.Files {
+		astutil.Apply(f, func(c *astutil.Cursor) bool {
+			if deleted {
+				return false
+			}
+			if c.Node() != n {
+				return true
+			}
+			if c.Index() < 0 {
+				return false
+			}
+			c.Delete()
+			deleted = true
+			return false
+		}, nil)
+
+		if deleted {
+			// A declaration without specs is not valid Go
+			a.Files[i].Decls = slices.DeleteFunc(f.Decls, func(decl ast.Decl) bool {
+				d, ok := decl.(*ast.GenDecl)
+				return ok && len(d.Specs) == 0
+			})
+
+			// Comments of the deleted node would otherwise resurface elsewhere
+			start := n.Pos()
+			if decl, ok := n.(ast.Decl); ok {
+				start = declStart(decl)
+			}
+			a.Files[i].Comments = slices.DeleteFunc(f.Comments, func(cg *ast.CommentGroup) bool {
+				return cg.Pos() >= start && (cg.End() <= n.End() || onEndLine(a.Fset, cg, n))
+			})
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not part of a list", nodeSummary(n))
+}
+
+// MoveDecl moves the top-level declaration containing n by delta positions
+// within its file
+func (a *Archive) MoveDecl(n ast.Node, delta int) error {
+	for _, f := range a.Files {
+		for i, decl := range f.Decls {
+			if !declContains(decl, n) {
+				continue
+			}
+			j := i + delta
+			if j < 0 || j >= len(f.Decls) {
+				return fmt.Errorf("cannot move %s any further", nodeSummary(decl))
+			}
+			f.Decls[i], f.Decls[j] = f.Decls[j], f.Decls[i]
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a top-level declaration", nodeSummary(n))
+}
+
+// counterpart returns the node of a with the type and extent of n, which
+// belongs to another parse of the same source
+func (a *Archive) counterpart(n ast.Node) ast.Node {
+	var found ast.Node
+	for _, f := range a.Files {
+		ast.Inspect(f, func(m ast.Node) bool {
+			if found != nil || m == nil || m.Pos() > n.Pos() || m.End() < n.End() {
+				return false
+			}
+			if m.Pos() == n.Pos() && m.End() == n.End() && reflect.TypeOf(m) == reflect.TypeOf(n) {
+				found = m
+				return false
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// declContains reports whether n is decl itself or one of its specs or spec names
+func declContains(decl ast.Decl, n ast.Node) bool {
+	if decl == n {
+		return true
+	}
+	d, ok := decl.(*ast.GenDecl)
+	if !ok {
+		return false
+	}
+	for _, spec := range d.Specs {
+		if spec == n {
+			return true
+		}
+		if vs, ok := spec.(*ast.ValueSpec); ok {
+			for _, name := range vs.Names {
+				if name == n {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Source prints the (possibly edited) files back with go/printer and
+// returns the regenerated txtar content
+func (a *Archive) Source() (string, error) {
+	ar := &txtar.Archive{
+		Comment: a.Txtar.Comment,
+		Files:   slices.Clone(a.Txtar.Files),
+	}
+
+	for _, f := range a.Files {
+		name := a.Fset.File(f.Pos()).Name()
+		src, err := printFile(a.Fset, f)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		for i, file := range ar.Files {
+			if file.Name == name {
+				ar.Files[i].Data = src
+			}
+		}
+	}
+
+	return string(txtar.Format(ar)), nil
+}
+
+// printFile prints a file declaration by declaration so that comments stay
+// with the declaration they belong to even after the declarations have
+// been reordered
+func printFile(fset *token.FileSet, f *ast.File) ([]byte, error) {
+	// Declarations in source order decide which comments they own
+	ordered := slices.Clone(f.Decls)
+	slices.SortFunc(ordered, func(a, b ast.Decl) int {
+		return cmp.Compare(declStart(a), declStart(b))
+	})
+
+	var header []*ast.CommentGroup
+	owned := make(map[ast.Decl][]*ast.CommentGroup)
+	for _, cg := range f.Comments {
+		if cg.End() <= f.Name.End() || len(ordered) == 0 {
+			header = append(header, cg)
+			continue
+		}
+		owner := ordered[len(ordered)-1]
+		for _, decl := range ordered {
+			if cg.Pos() < decl.End() || onEndLine(fset, cg, decl) {
+				owner = decl
+				break
+			}
+		}
+		owned[owner] = append(owned[owner], cg)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, &printer.CommentedNode{
+		Node: &ast.File{
+			Doc:     f.Doc,
+			Package: f.Package,
+			Name:    f.Name,
+		},
+		Comments: header,
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, decl := range f.Decls {
+		// The printer only places comments that fall inside the node, so
+		// free-floating comments around the declaration are written as is
+		var inner []*ast.CommentGroup
+		var line, trailing []*ast.CommentGroup
+		_, isFunc := decl.(*ast.FuncDecl)
+		buf.WriteString("\n\n")
+		for _, cg := range owned[decl] {
+			switch {
+			case cg.End() < declStart(decl):
+				writeCommentGroup(&buf, cg)
+				buf.WriteString("\n\n")
+			case onEndLine(fset, cg, decl) && isFunc:
+				line = append(line, cg)
+			case cg.Pos() >= decl.End() && !onEndLine(fset, cg, decl):
+				trailing = append(trailing, cg)
+			default:
+				// The line comment of a spec is part of the declaration for
+				// the printer
+				inner = append(inner, cg)
+			}
+		}
+		if err := printer.Fprint(&buf, fset, &printer.CommentedNode{
+			Node:     decl,
+			Comments: inner,
+		}); err != nil {
+			return nil, err
+		}
+		for _, cg := range line {
+			buf.WriteString(" ")
+			writeCommentGroup(&buf, cg)
+		}
+		for _, cg := range trailing {
+			buf.WriteString("\n\n")
+			writeCommentGroup(&buf, cg)
+		}
+	}
+	buf.WriteString("\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// onEndLine reports whether a comment group starts on the line n ends on,
+// after it
+func onEndLine(fset *token.FileSet, cg *ast.CommentGroup, n ast.Node) bool {
+	return cg.Pos() >= n.End() && fset.Position(cg.Pos()).Line == fset.Position(n.End()).Line
+}
+
+// writeCommentGroup writes the comments of a group verbatim
+func writeCommentGroup(buf *bytes.Buffer, cg *ast.CommentGroup) {
+	for i, c := range cg.List {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(c.Text)
+	}
+}
+
+// declStart returns the position of a declaration including its doc comment
+func declStart(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"testing"
+)
+
+// declNamed returns the top-level declaration that declares name
+func declNamed(t *testing.T, a *Archive, name string) ast.Decl {
+	t.Helper()
+	for _, f := range a.Files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Name.Name == name {
+					return d
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							if n.Name == name {
+								return d
+							}
+						}
+					case *ast.TypeSpec:
+						if s.Name.Name == name {
+							return d
+						}
+					}
+				}
+			}
+		}
+	}
+	t.Fatalf("no declaration of %s", name)
+	return nil
+}
+
+func TestEditRoundTrip(t *testing.T) {
+	const src = `-- a.go --
+package a
+
+// answer is documented
+const x = 1 // the answer
+
+var y = 2 // second
+
+// T is a type
+type T struct {
+	A int // field A
+}
+
+func f() {} // trailing
+
+// free comment
+
+func g() {
+	// inside
+}
+`
+
+	tests := []struct {
+		name string
+		edit func(t *testing.T, a *Archive) error
+		want string
+	}{
+		{
+			name: "unchanged",
+			edit: func(t *testing.T, a *Archive) error { return nil },
+			want: src,
+		},
+		{
+			name: "rename",
+			edit: func(t *testing.T, a *Archive) error {
+				return a.Rename(declNamed(t, a, "f"), "h")
+			},
+			want: `-- a.go --
+package a
+
+// answer is documented
+const x = 1 // the answer
+
+var y = 2 // second
+
+// T is a type
+type T struct {
+	A int // field A
+}
+
+func h() {} // trailing
+
+// free comment
+
+func g() {
+	// inside
+}
+`,
+		},
+		{
+			name: "delete",
+			edit: func(t *testing.T, a *Archive) error {
+				return a.Delete(declNamed(t, a, "x"))
+			},
+			want: `-- a.go --
+package a
+
+var y = 2 // second
+
+// T is a type
+type T struct {
+	A int // field A
+}
+
+func f() {} // trailing
+
+// free comment
+
+func g() {
+	// inside
+}
+`,
+		},
+		{
+			name: "move",
+			edit: func(t *testing.T, a *Archive) error {
+				return a.MoveDecl(declNamed(t, a, "x"), 1)
+			},
+			want: `-- a.go --
+package a
+
+var y = 2 // second
+
+// answer is documented
+const x = 1 // the answer
+
+// T is a type
+type T struct {
+	A int // field A
+}
+
+func f() {} // trailing
+
+// free comment
+
+func g() {
+	// inside
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseTxtar(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.edit(t, a); err != nil {
+				t.Fatal(err)
+			}
+			got, err := a.Source()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounterpart(t *testing.T) {
+	const src = "-- a.go --\npackage a\n\nfunc f(x int) int {\n\treturn (x)\n}\n"
+	a, err := ParseTxtar(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseTxtar(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret := declNamed(t, a, "f").(*ast.FuncDecl).Body.List[0].(*ast.ReturnStmt)
+	paren := ret.Results[0].(*ast.ParenExpr)
+	for _, n := range []ast.Node{ret, paren, paren.X} {
+		got := b.counterpart(n)
+		if got == nil || got == n || got.Pos() != n.Pos() || got.End() != n.End() {
+			t.Errorf("counterpart of %s = %v", nodeSummary(n), got)
+			continue
+		}
+		if _, ok := n.(*ast.Ident); ok && got.(*ast.Ident).Name != "x" {
+			t.Errorf("counterpart of x = %s", nodeSummary(got))
+		}
+	}
+}
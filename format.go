@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// FormatNodes prints every parsed file back with go/format and shows the
+// result together with a line diff against the original source
+func FormatNodes(a *Archive) []*ASTNode {
+	var nodes []*ASTNode
+
+	for _, f := range a.Files {
+		name := a.Fset.File(f.Pos()).Name()
+
+		var buf bytes.Buffer
+		if err := format.Node(&buf, a.Fset, f); err != nil {
+			nodes = append(nodes, &ASTNode{
+				Label:       fmt.Sprintf("File: %s (error: %v)", name, err),
+				IndentLevel: 1,
+			})
+			continue
+		}
+
+		original := splitLines(string(archiveFileData(a.Txtar, name)))
+		formatted := splitLines(buf.String())
+		hunks := diffHunks(lineDiff(original, formatted))
+
+		fileNode := &ASTNode{
+			Label:       fmt.Sprintf("File: %s (unchanged)", name),
+			IndentLevel: 1,
+			Node:        f,
+		}
+		if len(hunks) > 0 {
+			fileNode.Label = fmt.Sprintf("File: %s (%d hunks differ)", name, len(hunks))
+		}
+
+		formattedNode := &ASTNode{
+			Label:       "Formatted",
+			IndentLevel: 2,
+		}
+		for i, line := range formatted {
+			formattedNode.Children = append(formattedNode.Children, &ASTNode{
+				Label:       fmt.Sprintf("%4d  %s", i+1, line),
+				IndentLevel: 3,
+			})
+		}
+		fileNode.Children = append(fileNode.Children, formattedNode)
+
+		if len(hunks) > 0 {
+			fileNode.Children = append(fileNode.Children, diffToNode(hunks, 2))
+		}
+
+		nodes = append(nodes, fileNode)
+	}
+
+	return nodes
+}
+
+// FormatTxtar runs gofmt over every .go file of the archive and returns the
+// reassembled archive
+func FormatTxtar(content string) (string, error) {
+	ar := txtar.Parse([]byte(content))
+	for i, file := range ar.Files {
+		if !strings.HasSuffix(file.Name, ".go") {
+			continue
+		}
+		src, err := format.Source(file.Data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", file.Name, err)
+		}
+		ar.Files[i].Data = src
+	}
+	return string(txtar.Format(ar)), nil
+}
+
+// archiveFileData returns the content of the named archive entry
+func archiveFileData(ar *txtar.Archive, name string) []byte {
+	for _, file := range ar.Files {
+		if file.Name == name {
+			return file.Data
+		}
+	}
+	return nil
+}
+
+// splitLines splits text into lines without their terminators
+func splitLines(text string) []string {
+	var lines []string
+	for line := range strings.Lines(text) {
+		lines = append(lines, strings.TrimRight(line, "\n"))
+	}
+	return lines
+}
+
+// diffOp is the kind of a line in a diff
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+// diffLine is a single line of a line diff
+type diffLine struct {
+	Op      diffOp
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// lineDiff computes a line diff between old and new using the longest
+// common subsequence
+func lineDiff(old, new []string) []diffLine {
+	// lcs[i][j] is the LCS length of old[i:] and new[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			lines = append(lines, diffLine{Op: diffEqual, Text: old[i], OldLine: i + 1, NewLine: j + 1})
+			i++
+			j++
+		case i < len(old) && (j == len(new) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{Op: diffDelete, Text: old[i], OldLine: i + 1, NewLine: j})
+			i++
+		default:
+			lines = append(lines, diffLine{Op: diffInsert, Text: new[j], OldLine: i, NewLine: j + 1})
+			j++
+		}
+	}
+	return lines
+}
+
+// diffHunks groups consecutive changed lines of a diff
+func diffHunks(lines []diffLine) [][]diffLine {
+	var hunks [][]diffLine
+	var hunk []diffLine
+	for _, line := range lines {
+		if line.Op == diffEqual {
+			if hunk != nil {
+				hunks = append(hunks, hunk)
+				hunk = nil
+			}
+			continue
+		}
+		hunk = append(hunk, line)
+	}
+	if hunk != nil {
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// diffToNode renders diff hunks as display nodes
+func diffToNode(hunks [][]diffLine, level int) *ASTNode {
+	node := &ASTNode{
+		Label:       "Diff",
+		IndentLevel: level,
+	}
+	for _, hunk := range hunks {
+		oldStart, newStart := hunk[0].OldLine, hunk[0].NewLine
+		var deleted, inserted int
+		for _, line := range hunk {
+			switch line.Op {
+			case diffDelete:
+				if deleted == 0 {
+					oldStart = line.OldLine
+				}
+				deleted++
+			case diffInsert:
+				if inserted == 0 {
+					newStart = line.NewLine
+				}
+				inserted++
+			}
+		}
+		hunkNode := &ASTNode{
+			Label:       fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, deleted, newStart, inserted),
+			IndentLevel: level + 1,
+		}
+		for _, line := range hunk {
+			prefix := "+"
+			if line.Op == diffDelete {
+				prefix = "-"
+			}
+			hunkNode.Children = append(hunkNode.Children, &ASTNode{
+				Label:       prefix + " " + line.Text,
+				IndentLevel: level + 2,
+			})
+		}
+		node.Children = append(node.Children, hunkNode)
+	}
+	return node
+}
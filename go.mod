@@ -5,6 +5,7 @@ go 1.25.0
 require (
 	github.com/guigui-gui/guigui v0.0.0-20251130061309-90f026bf1b11
 	github.com/hajimehoshi/ebiten/v2 v2.10.0-alpha.4
+	golang.org/x/mod v0.30.0
 	golang.org/x/text v0.31.0
 	golang.org/x/tools v0.39.0
 )
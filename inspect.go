@@ -0,0 +1,318 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// maxSnippetLines limits the source lines shown for a node
+const maxSnippetLines = 20
+
+// InspectNodes describes a syntax node in detail: its Go type, the values of
+// its fields, its position, the nodes enclosing it, its source and, when the
+// archive has been type-checked, its type, object and method set
+func InspectNodes(a *Archive, ti *TypeInfo, n ast.Node) []*ASTNode {
+	nodes := []*ASTNode{
+		{
+			Label:       fmt.Sprintf("Go type: %T", n),
+			IndentLevel: 1,
+		},
+		fieldsToNode(a, n),
+		positionToNode(a, n),
+		parentsToNode(a, n),
+		snippetToNode(a, n),
+	}
+	if ti != nil {
+		nodes = append(nodes, typeInfoToNode(a, ti, n))
+	}
+	return nodes
+}
+
+// fieldsToNode lists the exported fields of a node and their values.
+// Elements of node lists are linked to their nodes.
+func fieldsToNode(a *Archive, n ast.Node) *ASTNode {
+	node := &ASTNode{
+		Label:       "Fields",
+		IndentLevel: 1,
+	}
+
+	v := reflect.ValueOf(n).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldNode := fieldValueToNode(a, field.Name, v.Field(i), 2)
+		node.Children = append(node.Children, fieldNode)
+
+		if v.Field(i).Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < v.Field(i).Len(); j++ {
+			fieldNode.Children = append(fieldNode.Children, fieldValueToNode(a, fmt.Sprintf("[%d]", j), v.Field(i).Index(j), 3))
+		}
+	}
+	return node
+}
+
+// fieldValueToNode renders one field or slice element
+func fieldValueToNode(a *Archive, name string, v reflect.Value, level int) *ASTNode {
+	node := &ASTNode{
+		IndentLevel: level,
+	}
+
+	switch {
+	case v.Type() == posType:
+		node.Label = fmt.Sprintf("%s: %s", name, positionString(a, v.Interface().(token.Pos)))
+		return node
+	case v.Type() == objectType:
+		if obj := v.Interface().(*ast.Object); obj != nil {
+			node.Label = fmt.Sprintf("%s: %s %s", name, obj.Kind, obj.Name)
+		} else {
+			node.Label = name + ": nil"
+		}
+		return node
+	case v.Type() == scopeType:
+		if scope := v.Interface().(*ast.Scope); scope != nil {
+			node.Label = fmt.Sprintf("%s: scope with %d objects", name, len(scope.Objects))
+		} else {
+			node.Label = name + ": nil"
+		}
+		return node
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			node.Label = name + ": nil"
+			return node
+		}
+		if child, ok := v.Interface().(ast.Node); ok {
+			node.Label = fmt.Sprintf("%s: %T %s", name, child, nodeText(child))
+			node.Node = child
+			return node
+		}
+	case reflect.Slice:
+		node.Label = fmt.Sprintf("%s: %s (len %d)", name, v.Type(), v.Len())
+		return node
+	}
+
+	node.Label = fmt.Sprintf("%s: %v", name, v.Interface())
+	return node
+}
+
+// nodeText returns a short rendering of a node
+func nodeText(n ast.Node) string {
+	switch n := n.(type) {
+	case ast.Expr:
+		return exprToString(n)
+	case *ast.Comment:
+		return commentSummary(&ast.CommentGroup{List: []*ast.Comment{n}})
+	case *ast.CommentGroup:
+		return commentSummary(n)
+	}
+	return nodeSummary(n)
+}
+
+// positionString renders a position, or "-" if it is invalid
+func positionString(a *Archive, pos token.Pos) string {
+	if !pos.IsValid() {
+		return "-"
+	}
+	return a.Fset.Position(pos).String()
+}
+
+// positionToNode shows where a node starts and ends
+func positionToNode(a *Archive, n ast.Node) *ASTNode {
+	start := a.Fset.Position(n.Pos())
+	end := a.Fset.Position(n.End())
+	return &ASTNode{
+		Label:       "Position",
+		IndentLevel: 1,
+		Children: []*ASTNode{
+			{
+				Label:       fmt.Sprintf("Pos: %s (offset %d)", start, start.Offset),
+				IndentLevel: 2,
+			},
+			{
+				Label:       fmt.Sprintf("End: %s (offset %d)", end, end.Offset),
+				IndentLevel: 2,
+			},
+		},
+	}
+}
+
+// parentsToNode lists the nodes enclosing a node, innermost first
+func parentsToNode(a *Archive, n ast.Node) *ASTNode {
+	node := &ASTNode{
+		Label:       "Parents",
+		IndentLevel: 1,
+	}
+
+	for _, f := range a.Files {
+		if n.Pos() < f.FileStart || n.Pos() > f.FileEnd {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(f, n.Pos(), n.End())
+		for _, parent := range path {
+			if parent == n {
+				continue
+			}
+			node.Children = append(node.Children, &ASTNode{
+				Label:       fmt.Sprintf("%T %s", parent, nodeText(parent)),
+				IndentLevel: 2,
+				Node:        parent,
+			})
+		}
+		break
+	}
+	return node
+}
+
+// snippetToNode shows the source lines a node spans
+func snippetToNode(a *Archive, n ast.Node) *ASTNode {
+	node := &ASTNode{
+		Label:       "Source",
+		IndentLevel: 1,
+	}
+
+	start := a.Fset.Position(n.Pos())
+	end := a.Fset.Position(n.End())
+	data := archiveFileData(a.Txtar, start.Filename)
+	if start.Offset > end.Offset || end.Offset > len(data) {
+		return node
+	}
+
+	lines := splitLines(string(data[start.Offset:end.Offset]))
+	for i, line := range lines {
+		if i == maxSnippetLines {
+			node.Children = append(node.Children, &ASTNode{
+				Label:       fmt.Sprintf("... (%d more lines)", len(lines)-i),
+				IndentLevel: 2,
+			})
+			break
+		}
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("%4d  %s", start.Line+i, strings.ReplaceAll(line, "\t", "    ")),
+			IndentLevel: 2,
+		})
+	}
+	return node
+}
+
+// typeInfoToNode shows what the type checker recorded for a node
+func typeInfoToNode(a *Archive, ti *TypeInfo, n ast.Node) *ASTNode {
+	node := &ASTNode{
+		Label:       "Types",
+		IndentLevel: 1,
+	}
+
+	var typ types.Type
+	if expr, ok := n.(ast.Expr); ok {
+		if tv, ok := ti.Info.Types[expr]; ok {
+			typ = tv.Type
+			node.Children = append(node.Children, &ASTNode{
+				Label:       fmt.Sprintf("Type: %s (%s)", types.TypeString(tv.Type, packageName), typeAndValueMode(tv)),
+				IndentLevel: 2,
+			})
+			if tv.Value != nil {
+				node.Children = append(node.Children, &ASTNode{
+					Label:       "Value: " + tv.Value.ExactString(),
+					IndentLevel: 2,
+				})
+			}
+		}
+	}
+
+	if obj := objectOf(ti, n); obj != nil {
+		objNode := &ASTNode{
+			Label:       "Object: " + types.ObjectString(obj, packageName),
+			IndentLevel: 2,
+		}
+		if obj.Pos().IsValid() {
+			objNode.Children = append(objNode.Children, &ASTNode{
+				Label:       "Declared at: " + positionString(a, obj.Pos()),
+				IndentLevel: 3,
+				Node:        a.displayedNodeAt(obj.Pos()),
+			})
+		}
+		node.Children = append(node.Children, objNode)
+		if _, ok := obj.(*types.TypeName); ok || typ == nil {
+			typ = obj.Type()
+		}
+	}
+
+	if typ != nil {
+		node.Children = append(node.Children, methodSetToNode(typ, 2))
+		if _, ok := typ.Underlying().(*types.Interface); !ok {
+			if _, ok := typ.(*types.Pointer); !ok {
+				node.Children = append(node.Children, methodSetToNode(types.NewPointer(typ), 2))
+			}
+		}
+	}
+
+	if len(node.Children) == 0 {
+		node.Label = "Types: nothing recorded"
+	}
+	return node
+}
+
+// objectOf returns the object a node defines or refers to
+func objectOf(ti *TypeInfo, n ast.Node) types.Object {
+	switch n := n.(type) {
+	case *ast.Ident:
+		return ti.Info.ObjectOf(n)
+	case *ast.SelectorExpr:
+		return ti.Info.ObjectOf(n.Sel)
+	case *ast.FuncDecl:
+		return ti.Info.Defs[n.Name]
+	case *ast.TypeSpec:
+		return ti.Info.Defs[n.Name]
+	case *ast.ImportSpec:
+		return ti.Info.PkgNameOf(n)
+	}
+	return ti.Info.Implicits[n]
+}
+
+// typeAndValueMode describes how an expression was classified
+func typeAndValueMode(tv types.TypeAndValue) string {
+	switch {
+	case tv.IsVoid():
+		return "void"
+	case tv.IsType():
+		return "type"
+	case tv.IsBuiltin():
+		return "builtin"
+	case tv.Value != nil:
+		return "constant"
+	case tv.IsNil():
+		return "nil"
+	case tv.Addressable():
+		return "variable"
+	}
+	return "value"
+}
+
+// methodSetToNode lists the method set of a type
+func methodSetToNode(typ types.Type, level int) *ASTNode {
+	mset := types.NewMethodSet(typ)
+	node := &ASTNode{
+		Label:       fmt.Sprintf("Method set of %s (%d)", types.TypeString(typ, packageName), mset.Len()),
+		IndentLevel: level,
+	}
+	for i := 0; i < mset.Len(); i++ {
+		node.Children = append(node.Children, &ASTNode{
+			Label:       types.SelectionString(mset.At(i), packageName),
+			IndentLevel: level + 1,
+		})
+	}
+	return node
+}
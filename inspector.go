@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"image"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// Inspector shows the details of the node selected in the tree
+type Inspector struct {
+	guigui.DefaultWidget
+
+	titleText  basicwidget.Text
+	detailList basicwidget.List[int]
+
+	title          string
+	nodes          []*ASTNode
+	listItems      []basicwidget.ListItem[int]
+	onNodeSelected func(n ast.Node)
+}
+
+// SetOnNodeSelected registers a callback for clicks on rows that refer to
+// another syntax node, such as a field value or a parent
+func (i *Inspector) SetOnNodeSelected(f func(n ast.Node)) {
+	i.onNodeSelected = f
+}
+
+// SetNodes replaces the details shown
+func (i *Inspector) SetNodes(title string, nodes []*ASTNode) {
+	i.title = title
+	i.nodes = nodes
+}
+
+func (i *Inspector) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	adder.AddChild(&i.titleText)
+	adder.AddChild(&i.detailList)
+
+	i.titleText.SetValue("Inspector: " + i.title)
+	i.titleText.SetBold(true)
+
+	i.listItems = treeListItems(i.listItems[:0], i.nodes)
+	i.detailList.SetItems(i.listItems)
+	i.detailList.SetStripeVisible(true)
+	i.detailList.SetOnItemSelected(func(index int) {
+		flatNodes := FlattenNodes(i.nodes)
+		if index < 0 || index >= len(flatNodes) {
+			return
+		}
+		node := flatNodes[index]
+		if node.Node != nil && i.onNodeSelected != nil {
+			i.onNodeSelected(node.Node)
+			return
+		}
+		if len(node.Children) > 0 {
+			node.Collapsed = !node.Collapsed
+		}
+	})
+	i.detailList.SetOnItemExpanderToggled(func(index int, expanded bool) {
+		flatNodes := FlattenNodes(i.nodes)
+		if index < len(flatNodes) {
+			flatNodes[index].Collapsed = !expanded
+		}
+	})
+
+	return nil
+}
+
+func (i *Inspector) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionVertical,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &i.titleText,
+			},
+			{
+				Widget: &i.detailList,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 4,
+	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+}
+
+func (i *Inspector) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	h := 12 * u
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
@@ -37,11 +37,16 @@ func main() {
 type LeftPanel struct {
 	guigui.DefaultWidget
 
-	titleText   basicwidget.Text
-	textInput   basicwidget.TextInput
-	parseButton basicwidget.Button
+	titleText        basicwidget.Text
+	textInput        basicwidget.TextInput
+	parseButton      basicwidget.Button
+	formatButton     basicwidget.Button
+	definitionButton basicwidget.Button
+	referencesButton basicwidget.Button
+	sampleButton     basicwidget.Button
 
 	onSourceChanged func(string)
+	onNavigate      func(nav navigation, source string, offset int)
 	currentSource   string
 	initialized     bool
 }
@@ -50,10 +55,59 @@ func (l *LeftPanel) SetOnSourceChanged(f func(string)) {
 	l.onSourceChanged = f
 }
 
+// SetOnNavigate registers a callback for navigation actions on the
+// identifier at the cursor
+func (l *LeftPanel) SetOnNavigate(f func(nav navigation, source string, offset int)) {
+	l.onNavigate = f
+}
+
+// navigate runs a navigation action on the identifier at the cursor
+func (l *LeftPanel) navigate(nav navigation) {
+	if l.onNavigate == nil {
+		return
+	}
+	start, _ := l.textInput.Selection()
+	l.onNavigate(nav, l.currentSource, start)
+}
+
+// SelectRange selects a byte range of the editor contents
+func (l *LeftPanel) SelectRange(start, end int) {
+	l.textInput.SetSelection(start, end)
+}
+
+// Source returns the editor contents
+func (l *LeftPanel) Source() string {
+	return l.currentSource
+}
+
+// SetSource replaces the editor contents and parses them
+func (l *LeftPanel) SetSource(source string) {
+	l.initialized = true
+	l.currentSource = source
+	l.textInput.SetValue(source)
+	if l.onSourceChanged != nil {
+		l.onSourceChanged(source)
+	}
+}
+
+// formatSource runs gofmt over the Go files in the editor
+func (l *LeftPanel) formatSource() {
+	formatted, err := FormatTxtar(l.currentSource)
+	if err != nil {
+		// Leave the source untouched; the tree already reports syntax errors
+		return
+	}
+	l.SetSource(formatted)
+}
+
 func (l *LeftPanel) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
 	adder.AddChild(&l.titleText)
 	adder.AddChild(&l.textInput)
 	adder.AddChild(&l.parseButton)
+	adder.AddChild(&l.formatButton)
+	adder.AddChild(&l.definitionButton)
+	adder.AddChild(&l.referencesButton)
+	adder.AddChild(&l.sampleButton)
 
 	l.titleText.SetValue("txtar Format Go Code:")
 	l.titleText.SetBold(true)
@@ -85,6 +139,24 @@ func (l *LeftPanel) Build(context *guigui.Context, adder *guigui.ChildAdder) err
 		}
 	})
 
+	l.formatButton.SetText("Format")
+	l.formatButton.SetOnDown(func() {
+		l.formatSource()
+	})
+
+	l.definitionButton.SetText("Definition")
+	l.definitionButton.SetOnDown(func() {
+		l.navigate(navigationDefinition)
+	})
+	l.referencesButton.SetText("References")
+	l.referencesButton.SetOnDown(func() {
+		l.navigate(navigationReferences)
+	})
+	l.sampleButton.SetText("Reset to Sample")
+	l.sampleButton.SetOnDown(func() {
+		l.SetSource(defaultSource)
+	})
+
 	return nil
 }
 
@@ -102,12 +174,37 @@ func (l *LeftPanel) Layout(context *guigui.Context, widgetBounds *guigui.WidgetB
 	}
 	layouter.LayoutWidget(&l.titleText, titleBounds)
 
-	// Button at bottom
+	// Buttons at bottom
 	buttonBounds := image.Rectangle{
 		Min: image.Pt(bounds.Min.X+u/2, bounds.Max.Y-u/2-buttonSize.Y),
 		Max: image.Pt(bounds.Max.X-u/2, bounds.Max.Y-u/2),
 	}
-	layouter.LayoutWidget(&l.parseButton, buttonBounds)
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &l.parseButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &l.formatButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &l.definitionButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &l.referencesButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &l.sampleButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 2,
+	}).LayoutWidgets(context, buttonBounds, layouter)
 
 	// TextInput in the middle
 	textBounds := image.Rectangle{
@@ -3,7 +3,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"go/ast"
 	"image"
 	"os"
 	"slices"
@@ -19,9 +21,22 @@ import (
 type Root struct {
 	guigui.DefaultWidget
 
-	background    basicwidget.Background
-	leftPanel     LeftPanel
-	rightPanel    RightPanel
+	background basicwidget.Background
+	tabBar     TabBar
+	tabs       []*Tab
+	current    int
+
+	// Panes of the current tab, side by side or stacked, with an optional
+	// third one. splitRatio is the share of the editor next to the tree and
+	// sideRatio that of the third pane, or 0 for the defaults.
+	sidePanel  SidePane
+	splitters  [2]Splitter
+	vertical   bool
+	sidePane   sidePaneKind
+	splitRatio float64
+	sideRatio  float64
+	paneBounds image.Rectangle
+	windowSize image.Point
 
 	locales           []language.Tag
 	faceSourceEntries []basicwidget.FaceSourceEntry
@@ -36,35 +51,106 @@ func (r *Root) updateFontFaceSources(context *guigui.Context) {
 }
 
 func (r *Root) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	if len(r.tabs) == 0 {
+		r.loadSession()
+	}
+	tab := r.currentTab()
+
 	adder.AddChild(&r.background)
-	adder.AddChild(&r.leftPanel)
-	adder.AddChild(&r.rightPanel)
+	adder.AddChild(&r.tabBar)
+	adder.AddChild(&tab.leftPanel)
+	adder.AddChild(&tab.rightPanel)
+	if r.sidePane != sidePaneNone {
+		adder.AddChild(&r.sidePanel)
+	}
+	for i := range r.paneCount() - 1 {
+		adder.AddChild(&r.splitters[i])
+		r.splitters[i].SetVertical(r.vertical)
+		r.splitters[i].SetOnDragged(func(pos image.Point) {
+			r.dragSplitter(i, pos)
+		})
+		r.splitters[i].SetOnDragEnded(func() {
+			r.saveSession()
+		})
+	}
 
 	r.updateFontFaceSources(context)
-	r.leftPanel.SetOnSourceChanged(func(source string) {
-		r.rightPanel.SetSource(source)
+	r.tabBar.SetTabs(r.tabNames(), r.current)
+	r.tabBar.SetOnSelected(func(index int) {
+		r.selectTab(index)
+	})
+	r.tabBar.SetOnNew(func() {
+		r.addTab()
+	})
+	r.tabBar.SetOnClose(func() {
+		r.closeTab()
+	})
+	r.tabBar.SetLayout(r.vertical, r.sidePane)
+	r.tabBar.SetOnLayoutChanged(func(vertical bool, sidePane sidePaneKind) {
+		r.vertical = vertical
+		r.sidePane = sidePane
+		r.saveSession()
+	})
+
+	if r.sidePane != sidePaneNone {
+		r.sidePanel.SetNodes(tab.rightPanel.SidePaneNodes(r.sidePane))
+		r.sidePanel.SetOnNodeSelected(func(n ast.Node) {
+			tab.rightPanel.revealNode(n)
+			tab.rightPanel.revealSource(n)
+		})
+	}
+
+	tab.leftPanel.SetOnSourceChanged(func(source string) {
+		tab.rightPanel.SetSource(source)
+	})
+	tab.rightPanel.SetOnSourceEdited(func(source string) {
+		tab.leftPanel.SetSource(source)
+	})
+	tab.leftPanel.SetOnNavigate(func(nav navigation, source string, offset int) {
+		tab.rightPanel.NavigateSource(nav, source, offset)
+	})
+	tab.rightPanel.SetOnRevealSource(func(start, end int) {
+		tab.leftPanel.SelectRange(start, end)
 	})
 	return nil
 }
 
 func (r *Root) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
 	layouter.LayoutWidget(&r.background, widgetBounds.Bounds())
-	(guigui.LinearLayout{
-		Direction: guigui.LayoutDirectionHorizontal,
-		Items: []guigui.LinearLayoutItem{
-			{
-				Widget: &r.leftPanel,
-				Size:   guigui.FlexibleSize(1),
-			},
-			{
-				Widget: &r.rightPanel,
-				Size:   guigui.FlexibleSize(1),
-			},
-		},
-	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+	bounds := widgetBounds.Bounds()
+	r.windowSize = bounds.Size()
+
+	// Tab bar at top
+	tabBarSize := r.tabBar.Measure(context, guigui.FixedWidthConstraints(bounds.Dx()))
+	tabBarBounds := image.Rectangle{
+		Min: bounds.Min,
+		Max: image.Pt(bounds.Max.X, bounds.Min.Y+tabBarSize.Y),
+	}
+	layouter.LayoutWidget(&r.tabBar, tabBarBounds)
+
+	// Panes of the current tab below, with splitters between them
+	tab := r.currentTab()
+	widgets := []guigui.Widget{&tab.leftPanel, &tab.rightPanel}
+	if r.sidePane != sidePaneNone {
+		widgets = append(widgets, &r.sidePanel)
+	}
+	r.paneBounds = image.Rectangle{
+		Min: image.Pt(bounds.Min.X, tabBarBounds.Max.Y),
+		Max: bounds.Max,
+	}
+	panes, splitters := paneRects(r.paneBounds, r.paneRatios(), r.vertical, basicwidget.UnitSize(context)/4)
+	for i, w := range widgets {
+		layouter.LayoutWidget(w, panes[i])
+	}
+	for i, s := range splitters {
+		layouter.LayoutWidget(&r.splitters[i], s)
+	}
 }
 
 func main() {
+	flag.IntVar(&maxExprLen, "maxlen", maxExprLen, "maximum length of expressions and comments shown in the tree, or 0 for no limit")
+	flag.Parse()
+
 	op := &guigui.RunOptions{
 		Title:      "Go AST Viewer",
 		WindowSize: image.Pt(1200, 800),
@@ -72,8 +158,17 @@ func main() {
 			ApplePressAndHoldEnabled: true,
 		},
 	}
-	if err := guigui.Run(&Root{}, op); err != nil {
+	root := &Root{}
+	root.loadSession()
+	if root.windowSize != (image.Point{}) {
+		op.WindowSize = root.windowSize
+	}
+	err := guigui.Run(root, op)
+	// Programs run in their own process group would outlive the viewer
+	root.stopRunners()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	root.saveSession()
 }
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// stdInterfacePackages are the standard library packages whose exported
+// interfaces are checked against the types of the archive
+var stdInterfacePackages = []string{
+	"container/heap",
+	"encoding",
+	"encoding/json",
+	"flag",
+	"fmt",
+	"io",
+	"sort",
+}
+
+// AnnotateMethodSets adds the method sets of T and *T to every named type
+// in the tree, along with the interfaces of the archive and the standard
+// library it implements and the ones it narrowly misses
+func AnnotateMethodSets(nodes []*ASTNode, ti *TypeInfo) {
+	ifaces := ti.interfaces()
+	var annotate func(nodes []*ASTNode)
+	annotate = func(nodes []*ASTNode) {
+		for _, node := range nodes {
+			annotate(node.Children)
+
+			ts, ok := node.Node.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			tn, ok := ti.Info.Defs[ts.Name].(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			methodsNode := methodSetsToNode(tn, ifaces, node.IndentLevel+1)
+			methodsNode.Parent = node
+			LinkParents(methodsNode.Children, methodsNode)
+			node.Children = append(node.Children, methodsNode)
+		}
+	}
+	annotate(nodes)
+}
+
+// interfaces returns the non-generic interfaces with methods declared at
+// package level in the archive and in stdInterfacePackages, plus error
+func (ti *TypeInfo) interfaces() []*types.TypeName {
+	ifaces := []*types.TypeName{
+		types.Universe.Lookup("error").(*types.TypeName),
+	}
+	add := func(scope *types.Scope, exportedOnly bool) {
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || (exportedOnly && !tn.Exported()) {
+				continue
+			}
+			iface, ok := tn.Type().Underlying().(*types.Interface)
+			if !ok || !iface.IsMethodSet() || iface.NumMethods() == 0 {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+			ifaces = append(ifaces, tn)
+		}
+	}
+
+	for _, pkg := range ti.Packages {
+		if pkg != nil {
+			add(pkg.Scope(), false)
+		}
+	}
+	if ti.importer != nil {
+		for _, path := range stdInterfacePackages {
+			if pkg, err := ti.importer.Import(path); err == nil {
+				add(pkg.Scope(), true)
+			}
+		}
+	}
+	return ifaces
+}
+
+// methodSetsToNode shows the method sets of a named type and the interfaces
+// it satisfies
+func methodSetsToNode(tn *types.TypeName, ifaces []*types.TypeName, level int) *ASTNode {
+	t := tn.Type()
+	ptr := types.NewPointer(t)
+	_, isInterface := t.Underlying().(*types.Interface)
+
+	node := &ASTNode{
+		Label:       "Methods and interfaces",
+		IndentLevel: level,
+		Collapsed:   true,
+		Annotation:  true,
+	}
+	node.Children = append(node.Children, methodSetToNode(t, level+1))
+	if !isInterface {
+		node.Children = append(node.Children, methodSetToNode(ptr, level+1))
+	}
+
+	// Implementation is only meaningful for instantiated types
+	if named, ok := t.(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return node
+	}
+
+	implementsNode := &ASTNode{
+		IndentLevel: level + 1,
+	}
+	nearNode := &ASTNode{
+		IndentLevel: level + 1,
+	}
+	for _, itn := range ifaces {
+		if itn == tn {
+			continue
+		}
+		iface := itn.Type().Underlying().(*types.Interface)
+		name := types.TypeString(itn.Type(), packageName)
+
+		switch {
+		case types.Implements(t, iface):
+			implementsNode.Children = append(implementsNode.Children, &ASTNode{
+				Label:       name,
+				IndentLevel: level + 2,
+			})
+		case !isInterface && types.Implements(ptr, iface):
+			implementsNode.Children = append(implementsNode.Children, &ASTNode{
+				Label:       fmt.Sprintf("%s (*%s only: %s)", name, tn.Name(), pointerOnlyReason(t, iface)),
+				IndentLevel: level + 2,
+			})
+		default:
+			if reason, ok := nearMiss(ptr, iface); ok {
+				nearNode.Children = append(nearNode.Children, &ASTNode{
+					Label:       fmt.Sprintf("%s: %s", name, reason),
+					IndentLevel: level + 2,
+				})
+			}
+		}
+	}
+	implementsNode.Label = fmt.Sprintf("Implements (%d)", len(implementsNode.Children))
+	nearNode.Label = fmt.Sprintf("Near misses (%d)", len(nearNode.Children))
+	node.Children = append(node.Children, implementsNode, nearNode)
+	return node
+}
+
+// pointerOnlyReason names the interface methods that T lacks because they
+// are declared with pointer receivers
+func pointerOnlyReason(t types.Type, iface *types.Interface) string {
+	mset := types.NewMethodSet(t)
+	var names []string
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if mset.Lookup(m.Pkg(), m.Name()) == nil {
+			names = append(names, m.Name())
+		}
+	}
+	if len(names) == 1 {
+		return names[0] + " has a pointer receiver"
+	}
+	return strings.Join(names, ", ") + " have pointer receivers"
+}
+
+// nearMiss reports whether t fails to implement iface by a single method,
+// and explains what is wrong with it
+func nearMiss(t types.Type, iface *types.Interface) (string, bool) {
+	var reasons []string
+	found := 0
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(t, false, m.Pkg(), m.Name())
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			reasons = append(reasons, "missing method "+m.Name())
+			continue
+		}
+		found++
+		if !types.Identical(fn.Type(), m.Type()) {
+			reasons = append(reasons, fmt.Sprintf("method %s has signature %s, want %s",
+				m.Name(),
+				types.TypeString(fn.Type(), packageName),
+				types.TypeString(m.Type(), packageName)))
+		}
+	}
+
+	// A single missing method only counts if the type has the others
+	if len(reasons) != 1 || (found == 0 && iface.NumMethods() > 0) {
+		return "", false
+	}
+	return reasons[0], true
+}
@@ -0,0 +1,396 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/txtar"
+)
+
+var embedMissingColor = diffDeletedColor
+
+// ModuleNodes shows the go.mod files of the archive as trees, the other
+// files it holds and the //go:embed directives of its Go files resolved
+// against those files
+func ModuleNodes(a *Archive) []*ASTNode {
+	var nodes []*ASTNode
+	filesNode := &ASTNode{
+		IndentLevel: 1,
+	}
+	for _, file := range a.Txtar.Files {
+		switch {
+		case isGoMod(file.Name):
+			nodes = append(nodes, goModToNode(file))
+		case !strings.HasSuffix(file.Name, ".go"):
+			filesNode.Children = append(filesNode.Children, rawFileToNode(file, 2))
+		}
+	}
+	filesNode.Label = fmt.Sprintf("Other files (%d)", len(filesNode.Children))
+	if len(nodes) == 0 {
+		nodes = append(nodes, &ASTNode{
+			Label:       "go.mod: none, a default module is used when building",
+			IndentLevel: 1,
+		})
+	}
+	nodes = append(nodes, filesNode, embedsToNode(a))
+	LinkParents(nodes, nil)
+	return nodes
+}
+
+func isGoMod(name string) bool {
+	return name == "go.mod" || strings.HasSuffix(name, "/go.mod")
+}
+
+// goModToNode shows the directives of a go.mod file
+func goModToNode(file txtar.File) *ASTNode {
+	node := &ASTNode{
+		Label:       "go.mod: " + file.Name,
+		IndentLevel: 1,
+	}
+	mf, err := modfile.Parse(file.Name, file.Data, nil)
+	if err != nil {
+		node.Label += fmt.Sprintf(" (error: %v)", err)
+		return node
+	}
+
+	add := func(label string) {
+		node.Children = append(node.Children, &ASTNode{
+			Label:       label,
+			IndentLevel: 2,
+		})
+	}
+	addList := func(title string, labels []string) {
+		if len(labels) == 0 {
+			return
+		}
+		list := &ASTNode{
+			Label:       fmt.Sprintf("%s (%d)", title, len(labels)),
+			IndentLevel: 2,
+		}
+		for _, label := range labels {
+			list.Children = append(list.Children, &ASTNode{
+				Label:       label,
+				IndentLevel: 3,
+			})
+		}
+		node.Children = append(node.Children, list)
+	}
+
+	if mf.Module != nil {
+		label := "Module: " + mf.Module.Mod.Path
+		if mf.Module.Deprecated != "" {
+			label += " (deprecated: " + mf.Module.Deprecated + ")"
+		}
+		add(label)
+	}
+	if mf.Go != nil {
+		add("Go: " + mf.Go.Version)
+	}
+	if mf.Toolchain != nil {
+		add("Toolchain: " + mf.Toolchain.Name)
+	}
+
+	var labels []string
+	for _, g := range mf.Godebug {
+		labels = append(labels, g.Key+"="+g.Value)
+	}
+	addList("Godebug", labels)
+
+	labels = nil
+	for _, r := range mf.Require {
+		label := moduleVersionString(r.Mod)
+		if r.Indirect {
+			label += " // indirect"
+		}
+		labels = append(labels, label)
+	}
+	addList("Require", labels)
+
+	labels = nil
+	for _, r := range mf.Replace {
+		labels = append(labels, moduleVersionString(r.Old)+" => "+moduleVersionString(r.New))
+	}
+	addList("Replace", labels)
+
+	labels = nil
+	for _, e := range mf.Exclude {
+		labels = append(labels, moduleVersionString(e.Mod))
+	}
+	addList("Exclude", labels)
+
+	labels = nil
+	for _, r := range mf.Retract {
+		label := r.Low
+		if r.High != r.Low {
+			label = "[" + r.Low + ", " + r.High + "]"
+		}
+		if r.Rationale != "" {
+			label += " // " + r.Rationale
+		}
+		labels = append(labels, label)
+	}
+	addList("Retract", labels)
+
+	labels = nil
+	for _, t := range mf.Tool {
+		labels = append(labels, t.Path)
+	}
+	addList("Tool", labels)
+
+	labels = nil
+	for _, i := range mf.Ignore {
+		labels = append(labels, i.Path)
+	}
+	addList("Ignore", labels)
+
+	return node
+}
+
+func moduleVersionString(v module.Version) string {
+	if v.Version == "" {
+		return v.Path
+	}
+	return v.Path + " " + v.Version
+}
+
+// rawFileToNode shows the size of a file and its first lines
+func rawFileToNode(file txtar.File, level int) *ASTNode {
+	lines := splitLines(string(file.Data))
+	node := &ASTNode{
+		Label:       fmt.Sprintf("%s (%d bytes, %d lines)", file.Name, len(file.Data), len(lines)),
+		IndentLevel: level,
+		Collapsed:   true,
+	}
+	for i, line := range lines {
+		if i == maxSnippetLines {
+			node.Children = append(node.Children, &ASTNode{
+				Label:       fmt.Sprintf("... (%d more lines)", len(lines)-i),
+				IndentLevel: level + 1,
+			})
+			break
+		}
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("%4d  %s", i+1, strings.ReplaceAll(line, "\t", "    ")),
+			IndentLevel: level + 1,
+		})
+	}
+	return node
+}
+
+// embedDirective is a //go:embed comment and the variable it applies to
+type embedDirective struct {
+	Comment  *ast.Comment
+	Spec     *ast.ValueSpec
+	Patterns []string
+	Err      error
+}
+
+// embedDirectives returns the //go:embed directives of a file
+func embedDirectives(f *ast.File) []embedDirective {
+	var directives []embedDirective
+	add := func(doc *ast.CommentGroup, spec *ast.ValueSpec) {
+		if doc == nil {
+			return
+		}
+		for _, c := range doc.List {
+			args, ok := strings.CutPrefix(c.Text, "//go:embed")
+			if !ok || (args != "" && args[0] != ' ' && args[0] != '\t') {
+				continue
+			}
+			patterns, err := parseEmbedPatterns(args)
+			directives = append(directives, embedDirective{
+				Comment:  c,
+				Spec:     spec,
+				Patterns: patterns,
+				Err:      err,
+			})
+		}
+	}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if len(gd.Specs) == 1 {
+				add(gd.Doc, vs)
+			}
+			add(vs.Doc, vs)
+		}
+	}
+	return directives
+}
+
+// parseEmbedPatterns splits the arguments of a //go:embed directive, which
+// may be quoted with double quotes or backquotes
+func parseEmbedPatterns(args string) ([]string, error) {
+	var patterns []string
+	for {
+		args = strings.TrimLeft(args, " \t")
+		if args == "" {
+			break
+		}
+		var pattern string
+		switch args[0] {
+		case '"', '`':
+			end := 1
+			for end < len(args) && args[end] != args[0] {
+				if args[0] == '"' && args[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(args) {
+				return patterns, fmt.Errorf("unterminated quoted pattern %s", args)
+			}
+			p, err := strconv.Unquote(args[:end+1])
+			if err != nil {
+				return patterns, fmt.Errorf("invalid quoted pattern %s", args[:end+1])
+			}
+			pattern, args = p, args[end+1:]
+		default:
+			i := strings.IndexAny(args, " \t")
+			if i < 0 {
+				i = len(args)
+			}
+			pattern, args = args[:i], args[i:]
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("missing patterns")
+	}
+	return patterns, nil
+}
+
+// embedMatches returns the archive files a //go:embed pattern of a package
+// in dir matches. Files in directories matched as a whole are skipped if
+// their name starts with . or _, unless the pattern has the all: prefix.
+func embedMatches(ar *txtar.Archive, dir, pattern string) ([]string, error) {
+	pattern, all := strings.CutPrefix(pattern, "all:")
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return nil, fmt.Errorf("invalid pattern %q: must be a clean relative path", pattern)
+		}
+	}
+
+	var matches []string
+	for _, file := range ar.Files {
+		rel := file.Name
+		if dir != "." {
+			var ok bool
+			if rel, ok = strings.CutPrefix(file.Name, dir+"/"); !ok {
+				continue
+			}
+		}
+		if inNestedModule(ar, dir, rel) {
+			continue
+		}
+		elems := strings.Split(rel, "/")
+		for i := range elems {
+			if ok, _ := path.Match(pattern, strings.Join(elems[:i+1], "/")); !ok {
+				continue
+			}
+			if i == len(elems)-1 || all || !hasHiddenElem(elems[i+1:]) {
+				matches = append(matches, file.Name)
+			}
+			break
+		}
+	}
+	return matches, nil
+}
+
+// inNestedModule reports whether a file at rel below dir belongs to another
+// module, which embedding cannot reach
+func inNestedModule(ar *txtar.Archive, dir, rel string) bool {
+	for d := path.Dir(rel); d != "."; d = path.Dir(d) {
+		goMod := path.Join(dir, d, "go.mod")
+		if slices.ContainsFunc(ar.Files, func(f txtar.File) bool { return f.Name == goMod }) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHiddenElem(elems []string) bool {
+	for _, elem := range elems {
+		if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// embedsToNode lists the //go:embed directives of the archive with the
+// files each pattern matches, flagging patterns that match nothing
+func embedsToNode(a *Archive) *ASTNode {
+	node := &ASTNode{
+		IndentLevel: 1,
+	}
+	for _, f := range a.Files {
+		name := a.Fset.File(f.Pos()).Name()
+		dir := path.Dir(name)
+		for _, d := range embedDirectives(f) {
+			dNode := &ASTNode{
+				Label:       fmt.Sprintf("%s: %s %s", positionString(a, d.Comment.Pos()), identNames(d.Spec.Names), d.Comment.Text),
+				IndentLevel: 2,
+				Node:        d.Spec,
+			}
+			if d.Err != nil {
+				dNode.Label += " (error: " + d.Err.Error() + ")"
+				dNode.Color = embedMissingColor
+			}
+			for _, pattern := range d.Patterns {
+				dNode.Children = append(dNode.Children, embedPatternToNode(a, dir, pattern, d.Spec))
+			}
+			for _, child := range dNode.Children {
+				if child.Color != nil {
+					dNode.Color = embedMissingColor
+				}
+			}
+			node.Children = append(node.Children, dNode)
+		}
+	}
+	node.Label = fmt.Sprintf("Embed directives (%d)", len(node.Children))
+	return node
+}
+
+// embedPatternToNode shows the files a pattern matches
+func embedPatternToNode(a *Archive, dir, pattern string, spec *ast.ValueSpec) *ASTNode {
+	node := &ASTNode{
+		IndentLevel: 3,
+		Node:        spec,
+	}
+	matches, err := embedMatches(a.Txtar, dir, pattern)
+	switch {
+	case err != nil:
+		node.Label = "Error: " + err.Error()
+		node.Color = embedMissingColor
+	case len(matches) == 0:
+		node.Label = fmt.Sprintf("%s: matches no file", pattern)
+		node.Color = embedMissingColor
+	default:
+		node.Label = fmt.Sprintf("%s (%d files)", pattern, len(matches))
+		for _, m := range matches {
+			node.Children = append(node.Children, &ASTNode{
+				Label:       m,
+				IndentLevel: 4,
+				Node:        spec,
+			})
+		}
+	}
+	return node
+}
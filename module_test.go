@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func TestParseEmbedPatterns(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    []string
+		wantErr bool
+	}{
+		{args: "a.txt", want: []string{"a.txt"}},
+		{args: " a.txt\tstatic/*  ", want: []string{"a.txt", "static/*"}},
+		{args: `"with space.txt" b`, want: []string{"with space.txt", "b"}},
+		{args: `"esc\"aped" ` + "`raw\\name`", want: []string{`esc"aped`, `raw\name`}},
+		{args: "", wantErr: true},
+		{args: `"unterminated`, wantErr: true},
+		{args: `"bad\q"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEmbedPatterns(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEmbedPatterns(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("parseEmbedPatterns(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedMatches(t *testing.T) {
+	ar := &txtar.Archive{}
+	for _, name := range []string{
+		"go.mod",
+		"main.go",
+		"hello.txt",
+		".hidden.txt",
+		"static/index.html",
+		"static/.keep",
+		"static/_draft.html",
+		"static/css/site.css",
+		"nested/go.mod",
+		"nested/data.txt",
+		"sub/main.go",
+		"sub/data.txt",
+	} {
+		ar.Files = append(ar.Files, txtar.File{Name: name})
+	}
+
+	tests := []struct {
+		dir, pattern string
+		want         []string
+		wantErr      bool
+	}{
+		{dir: ".", pattern: "hello.txt", want: []string{"hello.txt"}},
+		{dir: ".", pattern: "*.txt", want: []string{"hello.txt", ".hidden.txt"}},
+		{dir: ".", pattern: "static", want: []string{"static/index.html", "static/css/site.css"}},
+		{
+			dir:     ".",
+			pattern: "all:static",
+			want:    []string{"static/index.html", "static/.keep", "static/_draft.html", "static/css/site.css"},
+		},
+		{dir: ".", pattern: "static/.keep", want: []string{"static/.keep"}},
+		{dir: ".", pattern: "nested", want: nil},
+		{dir: "sub", pattern: "*.txt", want: []string{"sub/data.txt"}},
+		{dir: ".", pattern: "missing.txt", want: nil},
+		{dir: ".", pattern: "../hello.txt", wantErr: true},
+		{dir: ".", pattern: "static/", wantErr: true},
+		{dir: ".", pattern: "[", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := embedMatches(ar, tt.dir, tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("embedMatches(%q, %q) error = %v, want error %v", tt.dir, tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("embedMatches(%q, %q) = %q, want %q", tt.dir, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// identAt returns the identifier at pos, if any
+func (a *Archive) identAt(pos token.Pos) *ast.Ident {
+	path := a.enclosingNodes(pos)
+	if len(path) == 0 {
+		return nil
+	}
+	id, _ := path[0].(*ast.Ident)
+	return id
+}
+
+// inArchive reports whether pos lies in a Go file of the archive. Imported
+// packages are loaded into the same file set, so a known position alone
+// does not tell.
+func (a *Archive) inArchive(pos token.Pos) bool {
+	if !pos.IsValid() {
+		return false
+	}
+	file := a.Fset.File(pos)
+	return file != nil && slices.ContainsFunc(a.Files, func(f *ast.File) bool {
+		return a.Fset.File(f.Pos()) == file
+	})
+}
+
+// References returns the identifiers that declare or use obj across every
+// file of the archive, in source order. Methods and fields of generic types
+// are matched through their origin, so that uses on every instance count.
+func References(ti *TypeInfo, obj types.Object) []*ast.Ident {
+	obj = originObject(obj)
+	var idents []*ast.Ident
+	for id, def := range ti.Info.Defs {
+		if originObject(def) == obj {
+			idents = append(idents, id)
+		}
+	}
+	for id, use := range ti.Info.Uses {
+		if originObject(use) == obj {
+			idents = append(idents, id)
+		}
+	}
+	slices.SortFunc(idents, func(x, y *ast.Ident) int {
+		return cmp.Compare(x.Pos(), y.Pos())
+	})
+	return idents
+}
+
+// ReferenceNodes lists the definition of obj and every reference to it,
+// grouped by file, each linked to the node it occurs at
+func ReferenceNodes(a *Archive, ti *TypeInfo, obj types.Object) []*ASTNode {
+	defNode := &ASTNode{
+		Label:       "Definition: " + types.ObjectString(obj, packageName),
+		IndentLevel: 1,
+	}
+	if a.inArchive(obj.Pos()) {
+		defNode.Children = append(defNode.Children, referenceToNode(a, obj.Pos(), 2))
+	} else {
+		defNode.Label += " (outside the archive)"
+	}
+
+	idents := References(ti, obj)
+	refsNode := &ASTNode{
+		IndentLevel: 1,
+	}
+	var fileNode *ASTNode
+	var lastFile string
+	uses := 0
+	for _, id := range idents {
+		if _, ok := ti.Info.Defs[id]; ok {
+			continue
+		}
+		uses++
+		filename := a.Fset.Position(id.Pos()).Filename
+		if fileNode == nil || filename != lastFile {
+			lastFile = filename
+			fileNode = &ASTNode{
+				IndentLevel: 2,
+			}
+			refsNode.Children = append(refsNode.Children, fileNode)
+		}
+		fileNode.Children = append(fileNode.Children, referenceToNode(a, id.Pos(), 3))
+		fileNode.Label = fmt.Sprintf("File: %s (%d)", filename, len(fileNode.Children))
+	}
+	refsNode.Label = fmt.Sprintf("References (%d)", uses)
+
+	nodes := []*ASTNode{defNode, refsNode}
+	LinkParents(nodes, nil)
+	return nodes
+}
+
+// referenceToNode shows the source line at pos, linked to the innermost
+// node displayed in the tree
+func referenceToNode(a *Archive, pos token.Pos, level int) *ASTNode {
+	position := a.Fset.Position(pos)
+	lines := splitLines(string(archiveFileData(a.Txtar, position.Filename)))
+	line := ""
+	if position.Line <= len(lines) {
+		line = strings.TrimSpace(lines[position.Line-1])
+	}
+	return &ASTNode{
+		Label:       fmt.Sprintf("%d:%d: %s", position.Line, position.Column, line),
+		IndentLevel: level,
+		Node:        a.displayedNodeAt(pos),
+	}
+}
+
+// fileDataOffsets returns the offset in content at which the data of each
+// file of the archive starts
+func (a *Archive) fileDataOffsets(content string) map[string]int {
+	offsets := make(map[string]int)
+	cursor := 0
+	for _, file := range a.Txtar.Files {
+		marker := "-- " + file.Name + " --\n"
+		i := strings.Index(content[cursor:], marker)
+		if i < 0 {
+			break
+		}
+		cursor += i + len(marker)
+		offsets[file.Name] = cursor
+	}
+	return offsets
+}
+
+// PosAt converts an offset in the txtar content the archive was parsed from
+// into a position in one of its Go files
+func (a *Archive) PosAt(content string, offset int) token.Pos {
+	offsets := a.fileDataOffsets(content)
+	for _, f := range a.Files {
+		tf := a.Fset.File(f.Pos())
+		start, ok := offsets[tf.Name()]
+		if ok && start <= offset && offset <= start+tf.Size() {
+			return tf.Pos(offset - start)
+		}
+	}
+	return token.NoPos
+}
+
+// ContentOffset converts a position in one of the Go files of the archive
+// into an offset in the txtar content it was parsed from, or -1
+func (a *Archive) ContentOffset(content string, pos token.Pos) int {
+	tf := a.Fset.File(pos)
+	if tf == nil {
+		return -1
+	}
+	start, ok := a.fileDataOffsets(content)[tf.Name()]
+	if !ok {
+		return -1
+	}
+	return start + tf.Offset(pos)
+}
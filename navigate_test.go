@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+)
+
+func TestReferenceNodesOutsideArchive(t *testing.T) {
+	a, err := ParseTxtar(`-- main.go --
+package main
+
+import "fmt"
+
+func hello() {}
+
+func main() {
+	hello()
+	fmt.Println()
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := a.TypeCheck("")
+
+	for name, outside := range map[string]bool{"hello": false, "Println": true} {
+		obj := ti.Info.Uses[findIdent(t, a, name)]
+		if obj == nil {
+			t.Fatalf("no object for %s", name)
+		}
+		if got := a.inArchive(obj.Pos()); got == outside {
+			t.Errorf("inArchive(%s) = %v, want %v", name, got, !outside)
+		}
+		def := ReferenceNodes(a, ti, obj)[0]
+		if got := strings.HasSuffix(def.Label, "(outside the archive)"); got != outside {
+			t.Errorf("%s: definition row %q", name, def.Label)
+		}
+	}
+}
+
+// findIdent returns the last identifier named name in the archive
+func findIdent(t *testing.T, a *Archive, name string) *ast.Ident {
+	t.Helper()
+	var found *ast.Ident
+	for _, f := range a.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == name {
+				found = id
+			}
+			return true
+		})
+	}
+	if found == nil {
+		t.Fatalf("no identifier %s", name)
+	}
+	return found
+}
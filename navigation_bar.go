@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// navigation is a name resolution action on an identifier
+type navigation int
+
+const (
+	navigationDefinition navigation = iota
+	navigationReferences
+)
+
+// NavigationBar offers go-to-definition and find-references for the node
+// selected in the tree
+type NavigationBar struct {
+	guigui.DefaultWidget
+
+	definitionButton basicwidget.Button
+	referencesButton basicwidget.Button
+
+	onNavigate func(nav navigation)
+}
+
+func (b *NavigationBar) SetOnNavigate(f func(nav navigation)) {
+	b.onNavigate = f
+}
+
+func (b *NavigationBar) navigate(nav navigation) {
+	if b.onNavigate != nil {
+		b.onNavigate(nav)
+	}
+}
+
+func (b *NavigationBar) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	adder.AddChild(&b.definitionButton)
+	adder.AddChild(&b.referencesButton)
+
+	b.definitionButton.SetText("Go to Definition")
+	b.definitionButton.SetOnDown(func() {
+		b.navigate(navigationDefinition)
+	})
+	b.referencesButton.SetText("Find References")
+	b.referencesButton.SetOnDown(func() {
+		b.navigate(navigationReferences)
+	})
+
+	return nil
+}
+
+func (b *NavigationBar) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &b.definitionButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &b.referencesButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 4,
+	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+}
+
+func (b *NavigationBar) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	h := b.definitionButton.Measure(context, guigui.Constraints{}).Y
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// nodeEdit is an editing operation on the selected AST node
+type nodeEdit int
+
+const (
+	nodeEditRename nodeEdit = iota
+	nodeEditOperator
+	nodeEditDelete
+	nodeEditMoveUp
+	nodeEditMoveDown
+)
+
+// NodeEditor offers editing operations for the node selected in the tree
+type NodeEditor struct {
+	guigui.DefaultWidget
+
+	valueInput     basicwidget.TextInput
+	renameButton   basicwidget.Button
+	operatorButton basicwidget.Button
+	deleteButton   basicwidget.Button
+	upButton       basicwidget.Button
+	downButton     basicwidget.Button
+	statusText     basicwidget.Text
+
+	value  string
+	status string
+	onEdit func(edit nodeEdit, value string)
+}
+
+func (e *NodeEditor) SetOnEdit(f func(edit nodeEdit, value string)) {
+	e.onEdit = f
+}
+
+// SetStatus shows a message about the selection or the last edit
+func (e *NodeEditor) SetStatus(status string) {
+	e.status = status
+}
+
+func (e *NodeEditor) edit(edit nodeEdit) {
+	if e.onEdit != nil {
+		e.onEdit(edit, e.value)
+	}
+}
+
+func (e *NodeEditor) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	adder.AddChild(&e.valueInput)
+	adder.AddChild(&e.renameButton)
+	adder.AddChild(&e.operatorButton)
+	adder.AddChild(&e.deleteButton)
+	adder.AddChild(&e.upButton)
+	adder.AddChild(&e.downButton)
+	adder.AddChild(&e.statusText)
+
+	e.valueInput.SetOnValueChanged(func(text string, committed bool) {
+		e.value = text
+	})
+
+	e.renameButton.SetText("Rename")
+	e.renameButton.SetOnDown(func() {
+		e.edit(nodeEditRename)
+	})
+	e.operatorButton.SetText("Set Op")
+	e.operatorButton.SetOnDown(func() {
+		e.edit(nodeEditOperator)
+	})
+	e.deleteButton.SetText("Delete")
+	e.deleteButton.SetOnDown(func() {
+		e.edit(nodeEditDelete)
+	})
+	e.upButton.SetText("Move Up")
+	e.upButton.SetOnDown(func() {
+		e.edit(nodeEditMoveUp)
+	})
+	e.downButton.SetText("Move Down")
+	e.downButton.SetOnDown(func() {
+		e.edit(nodeEditMoveDown)
+	})
+
+	e.statusText.SetValue(e.status)
+
+	return nil
+}
+
+func (e *NodeEditor) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+	bounds := widgetBounds.Bounds()
+
+	rowHeight := e.renameButton.Measure(context, guigui.Constraints{}).Y
+	row := image.Rectangle{
+		Min: bounds.Min,
+		Max: image.Pt(bounds.Max.X, bounds.Min.Y+rowHeight),
+	}
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &e.valueInput,
+				Size:   guigui.FlexibleSize(2),
+			},
+			{
+				Widget: &e.renameButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &e.operatorButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &e.deleteButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &e.upButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &e.downButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 4,
+	}).LayoutWidgets(context, row, layouter)
+
+	statusBounds := image.Rectangle{
+		Min: image.Pt(bounds.Min.X, row.Max.Y+u/4),
+		Max: bounds.Max,
+	}
+	layouter.LayoutWidget(&e.statusText, statusBounds)
+}
+
+func (e *NodeEditor) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	rowHeight := e.renameButton.Measure(context, guigui.Constraints{}).Y
+	h := rowHeight + u/4 + u
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
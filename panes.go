@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+
+	"github.com/guigui-gui/guigui"
+)
+
+const (
+	defaultSplitRatio = 0.5
+	defaultSideRatio  = 0.25
+)
+
+// paneCount returns the number of panes shown for the current tab
+func (r *Root) paneCount() int {
+	if r.sidePane == sidePaneNone {
+		return 2
+	}
+	return 3
+}
+
+// paneRatios returns the share of the pane area given to the editor, the
+// tree and the third pane if it is open. The editor and the tree divide
+// what the third pane leaves at the split ratio.
+func (r *Root) paneRatios() []float64 {
+	split := r.splitRatio
+	if split <= 0 || split >= 1 {
+		split = defaultSplitRatio
+	}
+	if r.sidePane == sidePaneNone {
+		return []float64{split, 1 - split}
+	}
+	side := r.sideRatio
+	if side <= 0 || side >= 1 {
+		side = defaultSideRatio
+	}
+	return []float64{split * (1 - side), (1 - split) * (1 - side), side}
+}
+
+// dragSplitter moves the splitter at index to the cursor position pos
+func (r *Root) dragSplitter(index int, pos image.Point) {
+	area := r.paneBounds
+	var f float64
+	if r.vertical {
+		f = float64(pos.Y-area.Min.Y) / float64(max(area.Dy(), 1))
+	} else {
+		f = float64(pos.X-area.Min.X) / float64(max(area.Dx(), 1))
+	}
+
+	ratios := r.paneRatios()
+	switch index {
+	case 0:
+		if len(ratios) > 2 {
+			f /= 1 - ratios[2]
+		}
+		r.splitRatio = min(max(f, 0.1), 0.9)
+	case 1:
+		r.sideRatio = min(max(1-f, 0.1), 0.8)
+	}
+	guigui.RequestRebuild(r)
+}
+
+// paneRects divides area between panes by ratios, stacked when vertical is
+// set, leaving gap pixels between neighbours for the splitters
+func paneRects(area image.Rectangle, ratios []float64, vertical bool, gap int) (panes, splitters []image.Rectangle) {
+	start, end := area.Min.X, area.Max.X
+	if vertical {
+		start, end = area.Min.Y, area.Max.Y
+	}
+	available := end - start - gap*(len(ratios)-1)
+
+	span := func(from, to int) image.Rectangle {
+		if vertical {
+			return image.Rect(area.Min.X, from, area.Max.X, to)
+		}
+		return image.Rect(from, area.Min.Y, to, area.Max.Y)
+	}
+
+	pos := start
+	for i, ratio := range ratios {
+		next := pos + int(float64(available)*ratio)
+		if i == len(ratios)-1 {
+			next = end
+		}
+		panes = append(panes, span(pos, next))
+		if i < len(ratios)-1 {
+			splitters = append(splitters, span(next, next+gap))
+			next += gap
+		}
+		pos = next
+	}
+	return panes, splitters
+}
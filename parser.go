@@ -7,8 +7,11 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"image/color"
 	"reflect"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/tools/txtar"
 )
@@ -19,42 +22,92 @@ type ASTNode struct {
 	Children    []*ASTNode
 	IndentLevel int
 	Collapsed   bool
+
+	// Node is the syntax node this entry represents, if any
+	Node ast.Node
+
+	// Color overrides the text color of the row, if set
+	Color color.Color
+
+	// Excluded marks the row of a file the selected build context leaves
+	// out, which is greyed out unless the row is highlighted
+	Excluded bool
+
+	// Parent is the row this entry is nested under, or nil at the top level
+	Parent *ASTNode
+
+	// Annotation marks rows added after parsing, such as type information,
+	// so that they can be replaced when the information is recomputed
+	Annotation bool
+}
+
+// Archive holds the parsed content of a txtar archive
+type Archive struct {
+	Txtar *txtar.Archive
+	Fset  *token.FileSet
+	Files []*ast.File
+	Nodes []*ASTNode
+
+	// SyntaxErrors holds the errors of the Go files that failed to parse
+	SyntaxErrors []error
 }
 
-// ParseTxtar parses txtar content and returns AST nodes
-func ParseTxtar(content string) ([]*ASTNode, error) {
+// ParseTxtar parses txtar content and returns the parsed archive
+func ParseTxtar(content string) (*Archive, error) {
 	ar := txtar.Parse([]byte(content))
 
 	var nodes []*ASTNode
+	var files []*ast.File
+	var syntaxErrors []error
+	fset := token.NewFileSet()
 
 	for _, file := range ar.Files {
 		if !strings.HasSuffix(file.Name, ".go") {
 			continue
 		}
 
-		fset := token.NewFileSet()
 		f, err := parser.ParseFile(fset, file.Name, file.Data, parser.ParseComments)
 		if err != nil {
 			nodes = append(nodes, &ASTNode{
 				Label:       fmt.Sprintf("%s (error: %v)", file.Name, err),
 				IndentLevel: 1,
 			})
+			syntaxErrors = append(syntaxErrors, err)
 			continue
 		}
 
 		fileNode := &ASTNode{
 			Label:       fmt.Sprintf("File: %s", file.Name),
 			IndentLevel: 1,
+			Node:        f,
 		}
 		fileNode.Children = astToNodes(f, 2)
+		if cgoNode := cgoToNode(fset, f, 2); cgoNode != nil {
+			fileNode.Children = append(fileNode.Children, cgoNode)
+		}
+		if directivesNode := directivesToNode(fset, f, 2); directivesNode != nil {
+			fileNode.Children = append(fileNode.Children, directivesNode)
+		}
+		if commentsNode := commentsToNode(fset, f, 2); commentsNode != nil {
+			fileNode.Children = append(fileNode.Children, commentsNode)
+		}
 		nodes = append(nodes, fileNode)
+		files = append(files, f)
 	}
 
 	if len(nodes) == 0 {
 		return nil, fmt.Errorf("no .go files found in txtar content")
 	}
+	LinkParents(nodes, nil)
+
+	return &Archive{
+		Txtar: ar,
+		Fset:  fset,
+		Files: files,
+		Nodes: nodes,
 
-	return nodes, nil
+		SyntaxErrors: syntaxErrors,
+	}, nil
 }
 
 // astToNodes converts an AST node to our display nodes
@@ -68,10 +121,14 @@ func astToNodes(node ast.Node, level int) []*ASTNode {
 	switch n := node.(type) {
 	case *ast.File:
 		// Package name
-		nodes = append(nodes, &ASTNode{
+		pkgNode := &ASTNode{
 			Label:       fmt.Sprintf("Package: %s", n.Name.Name),
 			IndentLevel: level,
-		})
+		}
+		if docNode := docToNode(n.Doc, level+1); docNode != nil {
+			pkgNode.Children = append(pkgNode.Children, docNode)
+		}
+		nodes = append(nodes, pkgNode)
 
 		// Imports
 		if len(n.Imports) > 0 {
@@ -134,8 +191,15 @@ func genDeclToNode(d *ast.GenDecl, level int) []*ASTNode {
 				typeNode := &ASTNode{
 					Label:       fmt.Sprintf("Type: %s", ts.Name.Name),
 					IndentLevel: level,
+					Node:        ts,
+				}
+				if docNode := docToNode(specDoc(d, ts.Doc), level+1); docNode != nil {
+					typeNode.Children = append(typeNode.Children, docNode)
 				}
-				typeNode.Children = typeSpecToNodes(ts, level+1)
+				if ts.TypeParams != nil {
+					typeNode.Children = append(typeNode.Children, typeParamsToNode(ts.TypeParams, level+1))
+				}
+				typeNode.Children = append(typeNode.Children, typeSpecToNodes(ts, level+1)...)
 				nodes = append(nodes, typeNode)
 			}
 		}
@@ -143,14 +207,20 @@ func genDeclToNode(d *ast.GenDecl, level int) []*ASTNode {
 		constNode := &ASTNode{
 			Label:       "Const",
 			IndentLevel: level,
+			Node:        d,
 		}
 		for _, spec := range d.Specs {
 			if vs, ok := spec.(*ast.ValueSpec); ok {
 				for _, name := range vs.Names {
-					constNode.Children = append(constNode.Children, &ASTNode{
+					child := &ASTNode{
 						Label:       fmt.Sprintf("Const: %s", name.Name),
 						IndentLevel: level + 1,
-					})
+						Node:        name,
+					}
+					if docNode := docToNode(specDoc(d, vs.Doc), level+2); docNode != nil {
+						child.Children = append(child.Children, docNode)
+					}
+					constNode.Children = append(constNode.Children, child)
 				}
 			}
 		}
@@ -161,6 +231,7 @@ func genDeclToNode(d *ast.GenDecl, level int) []*ASTNode {
 		varNode := &ASTNode{
 			Label:       "Var",
 			IndentLevel: level,
+			Node:        d,
 		}
 		for _, spec := range d.Specs {
 			if vs, ok := spec.(*ast.ValueSpec); ok {
@@ -168,6 +239,10 @@ func genDeclToNode(d *ast.GenDecl, level int) []*ASTNode {
 					child := &ASTNode{
 						Label:       fmt.Sprintf("Var: %s", name.Name),
 						IndentLevel: level + 1,
+						Node:        name,
+					}
+					if docNode := docToNode(specDoc(d, vs.Doc), level+2); docNode != nil {
+						child.Children = append(child.Children, docNode)
 					}
 					if vs.Type != nil {
 						child.Children = append(child.Children, &ASTNode{
@@ -187,6 +262,15 @@ func genDeclToNode(d *ast.GenDecl, level int) []*ASTNode {
 	return nodes
 }
 
+// specDoc returns the doc comment of a spec, falling back to the doc comment
+// of its declaration when the declaration is not parenthesized
+func specDoc(d *ast.GenDecl, doc *ast.CommentGroup) *ast.CommentGroup {
+	if doc == nil && !d.Lparen.IsValid() {
+		return d.Doc
+	}
+	return doc
+}
+
 // typeSpecToNodes converts a type specification to display nodes
 func typeSpecToNodes(ts *ast.TypeSpec, level int) []*ASTNode {
 	var nodes []*ASTNode
@@ -212,6 +296,11 @@ func typeSpecToNodes(ts *ast.TypeSpec, level int) []*ASTNode {
 		}
 		if t.Methods != nil {
 			for _, method := range t.Methods.List {
+				// Embedded elements are interfaces or type sets
+				if len(method.Names) == 0 {
+					ifaceNode.Children = append(ifaceNode.Children, constraintToNode(method.Type, "Embedded", level+1))
+					continue
+				}
 				methodNode := fieldToNode(method, level+1)
 				ifaceNode.Children = append(ifaceNode.Children, methodNode)
 			}
@@ -219,8 +308,12 @@ func typeSpecToNodes(ts *ast.TypeSpec, level int) []*ASTNode {
 		nodes = append(nodes, ifaceNode)
 
 	default:
+		label := fmt.Sprintf("TypeExpr: %s", exprToString(ts.Type))
+		if ts.Assign.IsValid() {
+			label = fmt.Sprintf("Alias: %s", exprToString(ts.Type))
+		}
 		nodes = append(nodes, &ASTNode{
-			Label:       fmt.Sprintf("TypeExpr: %s", exprToString(ts.Type)),
+			Label:       label,
 			IndentLevel: level,
 		})
 	}
@@ -228,6 +321,64 @@ func typeSpecToNodes(ts *ast.TypeSpec, level int) []*ASTNode {
 	return nodes
 }
 
+// typeParamsToNode converts a type parameter list to a display node
+func typeParamsToNode(list *ast.FieldList, level int) *ASTNode {
+	node := &ASTNode{
+		Label:       "TypeParams",
+		IndentLevel: level,
+		Node:        list,
+	}
+	for _, field := range list.List {
+		paramNode := &ASTNode{
+			Label:       fmt.Sprintf("TypeParam: %s", identNames(field.Names)),
+			IndentLevel: level + 1,
+			Node:        field,
+		}
+		paramNode.Children = append(paramNode.Children, constraintToNode(field.Type, "Constraint", level+2))
+		node.Children = append(node.Children, paramNode)
+	}
+	return node
+}
+
+// constraintToNode converts a type constraint or embedded interface element
+// to a display node, splitting unions into their terms
+func constraintToNode(expr ast.Expr, title string, level int) *ASTNode {
+	terms := unionTerms(expr)
+	if len(terms) == 1 {
+		return &ASTNode{
+			Label:       fmt.Sprintf("%s: %s", title, exprToString(expr)),
+			IndentLevel: level,
+			Node:        expr,
+		}
+	}
+
+	node := &ASTNode{
+		Label:       fmt.Sprintf("%s: Union (%d terms)", title, len(terms)),
+		IndentLevel: level,
+		Node:        expr,
+	}
+	for _, term := range terms {
+		label := fmt.Sprintf("Term: %s", exprToString(term))
+		if u, ok := term.(*ast.UnaryExpr); ok && u.Op == token.TILDE {
+			label = fmt.Sprintf("Term: %s (underlying type)", exprToString(term))
+		}
+		node.Children = append(node.Children, &ASTNode{
+			Label:       label,
+			IndentLevel: level + 1,
+			Node:        term,
+		})
+	}
+	return node
+}
+
+// unionTerms flattens a union such as ~int | ~string into its terms
+func unionTerms(expr ast.Expr) []ast.Expr {
+	if b, ok := expr.(*ast.BinaryExpr); ok && b.Op == token.OR {
+		return append(unionTerms(b.X), unionTerms(b.Y)...)
+	}
+	return []ast.Expr{expr}
+}
+
 // fieldToNode converts a field to a display node
 func fieldToNode(field *ast.Field, level int) *ASTNode {
 	var name string
@@ -244,6 +395,11 @@ func fieldToNode(field *ast.Field, level int) *ASTNode {
 	node := &ASTNode{
 		Label:       fmt.Sprintf("Field: %s", name),
 		IndentLevel: level,
+		Node:        field,
+	}
+
+	if docNode := docToNode(field.Doc, level+1); docNode != nil {
+		node.Children = append(node.Children, docNode)
 	}
 
 	node.Children = append(node.Children, &ASTNode{
@@ -258,6 +414,13 @@ func fieldToNode(field *ast.Field, level int) *ASTNode {
 		})
 	}
 
+	if field.Comment != nil {
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("Comment: %s", commentSummary(field.Comment)),
+			IndentLevel: level + 1,
+		})
+	}
+
 	return node
 }
 
@@ -275,6 +438,16 @@ func funcDeclToNode(f *ast.FuncDecl, level int) *ASTNode {
 	node := &ASTNode{
 		Label:       label,
 		IndentLevel: level,
+		Node:        f,
+	}
+
+	if docNode := docToNode(f.Doc, level+1); docNode != nil {
+		node.Children = append(node.Children, docNode)
+	}
+
+	// Type parameters
+	if f.Type.TypeParams != nil {
+		node.Children = append(node.Children, typeParamsToNode(f.Type.TypeParams, level+1))
 	}
 
 	// Parameters
@@ -319,6 +492,7 @@ func stmtToNode(stmt ast.Stmt, level int) *ASTNode {
 	node := &ASTNode{
 		Label:       reflect.TypeOf(stmt).String(),
 		IndentLevel: level,
+		Node:        stmt,
 	}
 
 	switch s := stmt.(type) {
@@ -444,6 +618,7 @@ func exprToNode(expr ast.Expr, level int) *ASTNode {
 	node := &ASTNode{
 		Label:       exprToString(expr),
 		IndentLevel: level,
+		Node:        expr,
 	}
 
 	switch e := expr.(type) {
@@ -452,6 +627,7 @@ func exprToNode(expr ast.Expr, level int) *ASTNode {
 		node.Children = append(node.Children, &ASTNode{
 			Label:       fmt.Sprintf("Fun: %s", exprToString(e.Fun)),
 			IndentLevel: level + 1,
+			Node:        e.Fun,
 		})
 		if len(e.Args) > 0 {
 			argsNode := &ASTNode{
@@ -481,6 +657,13 @@ func exprToNode(expr ast.Expr, level int) *ASTNode {
 		node.Children = append(node.Children, exprToNode(e.X, level+1))
 		node.Children = append(node.Children, exprToNode(e.Index, level+1))
 
+	case *ast.IndexListExpr:
+		node.Label = "IndexListExpr"
+		node.Children = append(node.Children, exprToNode(e.X, level+1))
+		for _, index := range e.Indices {
+			node.Children = append(node.Children, exprToNode(index, level+1))
+		}
+
 	case *ast.CompositeLit:
 		node.Label = fmt.Sprintf("CompositeLit: %s", exprToString(e.Type))
 		for _, elt := range e.Elts {
@@ -498,6 +681,7 @@ func exprToNode(expr ast.Expr, level int) *ASTNode {
 		node.Children = append(node.Children, &ASTNode{
 			Label:       fmt.Sprintf("Key: %s", exprToString(e.Key)),
 			IndentLevel: level + 1,
+			Node:        e.Key,
 		})
 		node.Children = append(node.Children, exprToNode(e.Value, level+1))
 
@@ -529,8 +713,27 @@ func exprToNode(expr ast.Expr, level int) *ASTNode {
 	return node
 }
 
-// exprToString converts an expression to a string representation
+// maxExprLen is the length beyond which expressions and comments shown in
+// labels are elided, or 0 for no limit
+var maxExprLen = 80
+
+// exprToString converts an expression to a string representation, elided
+// to maxExprLen
 func exprToString(expr ast.Expr) string {
+	return elide(exprString(expr), maxExprLen)
+}
+
+// elide shortens s to at most n runes, marking the cut with an ellipsis
+func elide(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max(n-1, 0)]) + "…"
+}
+
+// exprString converts an expression to its full string representation
+func exprString(expr ast.Expr) string {
 	if expr == nil {
 		return ""
 	}
@@ -541,57 +744,124 @@ func exprToString(expr ast.Expr) string {
 	case *ast.BasicLit:
 		return e.Value
 	case *ast.SelectorExpr:
-		return fmt.Sprintf("%s.%s", exprToString(e.X), e.Sel.Name)
+		return fmt.Sprintf("%s.%s", exprString(e.X), e.Sel.Name)
 	case *ast.StarExpr:
-		return "*" + exprToString(e.X)
+		return "*" + exprString(e.X)
 	case *ast.ArrayType:
 		if e.Len != nil {
-			return fmt.Sprintf("[%s]%s", exprToString(e.Len), exprToString(e.Elt))
+			return fmt.Sprintf("[%s]%s", exprString(e.Len), exprString(e.Elt))
 		}
-		return "[]" + exprToString(e.Elt)
+		return "[]" + exprString(e.Elt)
 	case *ast.MapType:
-		return fmt.Sprintf("map[%s]%s", exprToString(e.Key), exprToString(e.Value))
+		return fmt.Sprintf("map[%s]%s", exprString(e.Key), exprString(e.Value))
 	case *ast.ChanType:
 		switch e.Dir {
 		case ast.SEND:
-			return "chan<- " + exprToString(e.Value)
+			return "chan<- " + exprString(e.Value)
 		case ast.RECV:
-			return "<-chan " + exprToString(e.Value)
+			return "<-chan " + exprString(e.Value)
 		default:
-			return "chan " + exprToString(e.Value)
+			return "chan " + exprString(e.Value)
 		}
 	case *ast.FuncType:
-		return "func(...)"
+		return "func" + signatureString(e)
 	case *ast.InterfaceType:
-		return "interface{}"
+		elems := make([]string, len(e.Methods.List))
+		for i, field := range e.Methods.List {
+			if ft, ok := field.Type.(*ast.FuncType); ok && len(field.Names) > 0 {
+				elems[i] = field.Names[0].Name + signatureString(ft)
+			} else {
+				elems[i] = exprString(field.Type)
+			}
+		}
+		return bracedString("interface", elems)
 	case *ast.StructType:
-		return "struct{...}"
+		fields := make([]string, len(e.Fields.List))
+		for i, field := range e.Fields.List {
+			fields[i] = fieldString(field)
+			if field.Tag != nil {
+				fields[i] += " " + field.Tag.Value
+			}
+		}
+		return bracedString("struct", fields)
 	case *ast.Ellipsis:
-		return "..." + exprToString(e.Elt)
+		return "..." + exprString(e.Elt)
 	case *ast.CallExpr:
-		return fmt.Sprintf("%s(...)", exprToString(e.Fun))
+		return fmt.Sprintf("%s(...)", exprString(e.Fun))
 	case *ast.IndexExpr:
-		return fmt.Sprintf("%s[%s]", exprToString(e.X), exprToString(e.Index))
+		return fmt.Sprintf("%s[%s]", exprString(e.X), exprString(e.Index))
 	case *ast.IndexListExpr:
-		return fmt.Sprintf("%s[...]", exprToString(e.X))
+		indices := make([]string, len(e.Indices))
+		for i, index := range e.Indices {
+			indices[i] = exprString(index)
+		}
+		return fmt.Sprintf("%s[%s]", exprString(e.X), strings.Join(indices, ", "))
 	case *ast.BinaryExpr:
-		return fmt.Sprintf("%s %s %s", exprToString(e.X), e.Op.String(), exprToString(e.Y))
+		return fmt.Sprintf("%s %s %s", exprString(e.X), e.Op.String(), exprString(e.Y))
 	case *ast.UnaryExpr:
-		return fmt.Sprintf("%s%s", e.Op.String(), exprToString(e.X))
+		return fmt.Sprintf("%s%s", e.Op.String(), exprString(e.X))
 	case *ast.ParenExpr:
-		return fmt.Sprintf("(%s)", exprToString(e.X))
+		return fmt.Sprintf("(%s)", exprString(e.X))
 	case *ast.CompositeLit:
 		if e.Type != nil {
-			return fmt.Sprintf("%s{...}", exprToString(e.Type))
+			return fmt.Sprintf("%s{...}", exprString(e.Type))
 		}
 		return "{...}"
 	case *ast.FuncLit:
-		return "func(){...}"
+		return fmt.Sprintf("func%s {...}", signatureString(e.Type))
 	default:
 		return fmt.Sprintf("%T", expr)
 	}
 }
 
+// signatureString renders the type parameters, parameters and results of a
+// function type
+func signatureString(ft *ast.FuncType) string {
+	var b strings.Builder
+	if ft.TypeParams != nil {
+		fmt.Fprintf(&b, "[%s]", fieldListString(ft.TypeParams))
+	}
+	fmt.Fprintf(&b, "(%s)", fieldListString(ft.Params))
+
+	if ft.Results != nil {
+		results := ft.Results.List
+		if len(results) == 1 && len(results[0].Names) == 0 {
+			b.WriteString(" " + exprString(results[0].Type))
+		} else if len(results) > 0 {
+			fmt.Fprintf(&b, " (%s)", fieldListString(ft.Results))
+		}
+	}
+	return b.String()
+}
+
+// fieldListString renders a parameter, result or type parameter list
+func fieldListString(list *ast.FieldList) string {
+	if list == nil {
+		return ""
+	}
+	fields := make([]string, len(list.List))
+	for i, field := range list.List {
+		fields[i] = fieldString(field)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// fieldString renders the names and type of a field
+func fieldString(field *ast.Field) string {
+	if len(field.Names) == 0 {
+		return exprString(field.Type)
+	}
+	return fmt.Sprintf("%s %s", identNames(field.Names), exprString(field.Type))
+}
+
+// bracedString renders an interface or struct type from its elements
+func bracedString(keyword string, elems []string) string {
+	if len(elems) == 0 {
+		return keyword + "{}"
+	}
+	return fmt.Sprintf("%s{ %s }", keyword, strings.Join(elems, "; "))
+}
+
 // FlattenNodes flattens the tree structure for list display
 func FlattenNodes(nodes []*ASTNode) []*ASTNode {
 	var result []*ASTNode
@@ -610,3 +880,46 @@ func flattenNode(node *ASTNode) []*ASTNode {
 	}
 	return result
 }
+
+// LinkParents sets the Parent of every node in a tree
+func LinkParents(nodes []*ASTNode, parent *ASTNode) {
+	for _, node := range nodes {
+		node.Parent = parent
+		LinkParents(node.Children, node)
+	}
+}
+
+// Ancestors returns the chain of rows from the top level down to node,
+// including node itself
+func (node *ASTNode) Ancestors() []*ASTNode {
+	var path []*ASTNode
+	for n := node; n != nil; n = n.Parent {
+		path = append(path, n)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// ClearAnnotations removes the annotation rows from a tree
+func ClearAnnotations(nodes []*ASTNode) {
+	for _, node := range nodes {
+		node.Children = slices.DeleteFunc(node.Children, func(child *ASTNode) bool {
+			return child.Annotation
+		})
+		ClearAnnotations(node.Children)
+	}
+}
+
+// FindPath returns the chain of display nodes from a root down to the node
+// that represents n, or nil if n is not displayed
+func FindPath(nodes []*ASTNode, n ast.Node) []*ASTNode {
+	for _, node := range nodes {
+		if node.Node == n {
+			return []*ASTNode{node}
+		}
+		if path := FindPath(node.Children, n); path != nil {
+			return append([]*ASTNode{node}, path...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// RewriteRule replaces expressions matching Pattern with Replacement.
+// As with gofmt -r, single-character lowercase identifiers are wildcards
+// that match any expression and are substituted in the replacement.
+type RewriteRule struct {
+	Text        string
+	Pattern     ast.Expr
+	Replacement ast.Expr
+}
+
+// ParseRewriteRules parses one "pattern -> replacement" rule per line.
+// Blank lines and lines starting with // are ignored.
+func ParseRewriteRules(text string) ([]RewriteRule, error) {
+	var rules []RewriteRule
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		pattern, replacement, ok := strings.Cut(line, "->")
+		if !ok {
+			return nil, fmt.Errorf("line %d: rule must have the form 'pattern -> replacement'", i+1)
+		}
+		patternExpr, err := parser.ParseExpr(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: pattern: %w", i+1, err)
+		}
+		replacementExpr, err := parser.ParseExpr(strings.TrimSpace(replacement))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: replacement: %w", i+1, err)
+		}
+
+		rules = append(rules, RewriteRule{
+			Text:        line,
+			Pattern:     patternExpr,
+			Replacement: replacementExpr,
+		})
+	}
+
+	return rules, nil
+}
+
+// Rewrite applies the rules to every file of the archive with astutil.Apply
+// and returns the number of replacements made by each rule
+func (a *Archive) Rewrite(rules []RewriteRule) []int {
+	counts := make([]int, len(rules))
+
+	for _, f := range a.Files {
+		astutil.Apply(f, nil, func(c *astutil.Cursor) bool {
+			expr, ok := c.Node().(ast.Expr)
+			if !ok {
+				return true
+			}
+			for i, rule := range rules {
+				m := make(map[string]reflect.Value)
+				if !match(m, reflect.ValueOf(rule.Pattern), reflect.ValueOf(expr)) {
+					continue
+				}
+				repl := subst(m, reflect.ValueOf(rule.Replacement), expr.Pos()).Interface().(ast.Expr)
+				if !replaceable(c, repl) {
+					continue
+				}
+				c.Replace(repl)
+				counts[i]++
+				break
+			}
+			return true
+		})
+	}
+
+	return counts
+}
+
+// replaceable reports whether the cursor's field can hold n
+func replaceable(c *astutil.Cursor, n ast.Node) bool {
+	field := reflect.ValueOf(c.Parent()).Elem().FieldByName(c.Name())
+	if !field.IsValid() {
+		return false
+	}
+	t := field.Type()
+	if c.Index() >= 0 {
+		t = t.Elem()
+	}
+	return reflect.TypeOf(n).AssignableTo(t)
+}
+
+var (
+	identType  = reflect.TypeFor[*ast.Ident]()
+	objectType = reflect.TypeFor[*ast.Object]()
+	scopeType  = reflect.TypeFor[*ast.Scope]()
+	posType    = reflect.TypeFor[token.Pos]()
+)
+
+// isWildcard reports whether name is a wildcard in rewrite rules
+func isWildcard(name string) bool {
+	r, size := utf8.DecodeRuneInString(name)
+	return size == len(name) && unicode.IsLower(r)
+}
+
+// match reports whether pattern matches val, recording wildcard bindings in m
+func match(m map[string]reflect.Value, pattern, val reflect.Value) bool {
+	// Wildcards match any expression, but the same one every time
+	if m != nil && pattern.IsValid() && pattern.Type() == identType {
+		name := pattern.Interface().(*ast.Ident).Name
+		if isWildcard(name) && val.IsValid() {
+			if _, ok := val.Interface().(ast.Expr); ok {
+				if old, ok := m[name]; ok {
+					return match(nil, old, val)
+				}
+				m[name] = val
+				return true
+			}
+		}
+	}
+
+	if !pattern.IsValid() || !val.IsValid() {
+		return !pattern.IsValid() && !val.IsValid()
+	}
+	if pattern.Type() != val.Type() {
+		return false
+	}
+
+	// Positions and resolved objects do not take part in matching
+	switch pattern.Type() {
+	case identType:
+		return pattern.Interface().(*ast.Ident).Name == val.Interface().(*ast.Ident).Name
+	case objectType, posType:
+		return true
+	}
+
+	switch pattern.Kind() {
+	case reflect.Slice:
+		if pattern.Len() != val.Len() {
+			return false
+		}
+		for i := 0; i < pattern.Len(); i++ {
+			if !match(m, pattern.Index(i), val.Index(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Struct:
+		for i := 0; i < pattern.NumField(); i++ {
+			if !match(m, pattern.Field(i), val.Field(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Interface, reflect.Pointer:
+		if pattern.IsNil() || val.IsNil() {
+			return pattern.IsNil() && val.IsNil()
+		}
+		return match(m, pattern.Elem(), val.Elem())
+	}
+
+	return pattern.Interface() == val.Interface()
+}
+
+// subst returns a copy of pattern with wildcards replaced by their bindings
+// in m and positions set to pos
+func subst(m map[string]reflect.Value, pattern reflect.Value, pos token.Pos) reflect.Value {
+	if !pattern.IsValid() {
+		return reflect.Value{}
+	}
+
+	if pattern.Type() == identType {
+		if old, ok := m[pattern.Interface().(*ast.Ident).Name]; ok {
+			return old
+		}
+	}
+
+	switch pattern.Type() {
+	case posType:
+		// Invalid positions such as a missing CallExpr.Ellipsis stay invalid
+		if !pattern.Interface().(token.Pos).IsValid() {
+			return pattern
+		}
+		return reflect.ValueOf(pos)
+	case objectType:
+		return reflect.Zero(objectType)
+	}
+
+	switch pattern.Kind() {
+	case reflect.Slice:
+		if pattern.IsNil() {
+			return reflect.Zero(pattern.Type())
+		}
+		v := reflect.MakeSlice(pattern.Type(), pattern.Len(), pattern.Len())
+		for i := 0; i < pattern.Len(); i++ {
+			v.Index(i).Set(subst(m, pattern.Index(i), pos))
+		}
+		return v
+
+	case reflect.Struct:
+		v := reflect.New(pattern.Type()).Elem()
+		for i := 0; i < pattern.NumField(); i++ {
+			v.Field(i).Set(subst(m, pattern.Field(i), pos))
+		}
+		return v
+
+	case reflect.Pointer:
+		if pattern.IsNil() {
+			return reflect.Zero(pattern.Type())
+		}
+		v := reflect.New(pattern.Type().Elem())
+		v.Elem().Set(subst(m, pattern.Elem(), pos))
+		return v
+
+	case reflect.Interface:
+		if pattern.IsNil() {
+			return reflect.Zero(pattern.Type())
+		}
+		v := reflect.New(pattern.Type()).Elem()
+		v.Set(subst(m, pattern.Elem(), pos))
+		return v
+	}
+
+	return pattern
+}
+
+// RewriteNodes applies the rules to a fresh parse of source and shows the
+// AST before and after together with the source diff. It also returns the
+// rewritten source.
+func RewriteNodes(source string, rules []RewriteRule) ([]*ASTNode, string, error) {
+	before, err := ParseTxtar(source)
+	if err != nil {
+		return nil, "", err
+	}
+	work, err := ParseTxtar(source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Without replacements the source is left exactly as it was rather
+	// than printed again
+	counts := work.Rewrite(rules)
+	rewritten, after := source, work
+	if slices.ContainsFunc(counts, func(n int) bool { return n > 0 }) {
+		rewritten, err = work.Source()
+		if err != nil {
+			return nil, "", err
+		}
+		after, err = ParseTxtar(rewritten)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	rulesNode := &ASTNode{
+		Label:       "Rules",
+		IndentLevel: 1,
+	}
+	for i, rule := range rules {
+		rulesNode.Children = append(rulesNode.Children, &ASTNode{
+			Label:       fmt.Sprintf("%s (%d replacements)", rule.Text, counts[i]),
+			IndentLevel: 2,
+		})
+	}
+
+	beforeNode := &ASTNode{
+		Label:       "Before",
+		Children:    shiftLevels(before.Nodes, 1),
+		IndentLevel: 1,
+		Collapsed:   true,
+	}
+	afterNode := &ASTNode{
+		Label:       "After",
+		Children:    shiftLevels(after.Nodes, 1),
+		IndentLevel: 1,
+		Collapsed:   true,
+	}
+
+	diffNode := &ASTNode{
+		Label:       "Source diff",
+		IndentLevel: 1,
+	}
+	for _, file := range after.Txtar.Files {
+		old := splitLines(string(archiveFileData(before.Txtar, file.Name)))
+		hunks := diffHunks(lineDiff(old, splitLines(string(file.Data))))
+		if len(hunks) == 0 {
+			continue
+		}
+		fileNode := diffToNode(hunks, 2)
+		fileNode.Label = fmt.Sprintf("File: %s (%d hunks)", file.Name, len(hunks))
+		diffNode.Children = append(diffNode.Children, fileNode)
+	}
+
+	return []*ASTNode{rulesNode, beforeNode, afterNode, diffNode}, rewritten, nil
+}
+
+// shiftLevels increases the indent level of a subtree by delta in place
+func shiftLevels(nodes []*ASTNode, delta int) []*ASTNode {
+	for _, node := range nodes {
+		node.IndentLevel += delta
+		shiftLevels(node.Children, delta)
+	}
+	return nodes
+}
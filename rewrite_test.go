@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	const src = `-- a.go --
+package a
+
+const c = 1 // the answer
+
+func f(s []int, x, y int) int {
+	_ = s[0:len(s)]
+	return x + y + x*1
+}
+`
+
+	tests := []struct {
+		name   string
+		rules  string
+		counts []int
+		want   string
+	}{
+		{
+			name:   "no match leaves the source alone",
+			rules:  "a - b -> b - a",
+			counts: []int{0},
+			want:   src,
+		},
+		{
+			name:   "wildcards bind consistently",
+			rules:  "s[0:len(s)] -> s[:]\nx * 1 -> x",
+			counts: []int{1, 1},
+			want: `-- a.go --
+package a
+
+const c = 1 // the answer
+
+func f(s []int, x, y int) int {
+	_ = s[:]
+	return x + y + x
+}
+`,
+		},
+		{
+			name:   "repeated wildcard needs equal expressions",
+			rules:  "a + a -> 2 * a",
+			counts: []int{0},
+			want:   src,
+		},
+		{
+			name:   "longer identifiers match by name",
+			rules:  "len(q) -> cap(q)\nlength(q) -> q",
+			counts: []int{1, 0},
+			want: `-- a.go --
+package a
+
+const c = 1 // the answer
+
+func f(s []int, x, y int) int {
+	_ = s[0:cap(s)]
+	return x + y + x*1
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRewriteRules(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := ParseTxtar(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts := a.Rewrite(rules); !slices.Equal(counts, tt.counts) {
+				t.Errorf("counts = %v, want %v", counts, tt.counts)
+			}
+
+			nodes, got, err := RewriteNodes(src, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			diffNode := nodes[len(nodes)-1]
+			if hasDiff := len(diffNode.Children) > 0; hasDiff != (tt.want != src) {
+				t.Errorf("source diff has %d files", len(diffNode.Children))
+			}
+		})
+	}
+}
+
+func TestParseRewriteRulesErrors(t *testing.T) {
+	for _, text := range []string{
+		"x + y",
+		"x + -> y",
+		"x -> y +",
+	} {
+		if _, err := ParseRewriteRules(text); err == nil {
+			t.Errorf("ParseRewriteRules(%q) succeeded", text)
+		}
+	}
+}
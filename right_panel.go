@@ -3,24 +3,159 @@
 package main
 
 import (
+	"fmt"
+	"go/ast"
+	"go/types"
 	"image"
+	"os"
 
 	"github.com/guigui-gui/guigui"
 	"github.com/guigui-gui/guigui/basicwidget"
 )
 
+// viewMode selects what the right panel displays
+type viewMode int
+
+const (
+	viewModeAST viewMode = iota
+	viewModeDocs
+	viewModeFormatted
+	viewModeRewrite
+	viewModeASTDiff
+	viewModeVersions
+	viewModeScopes
+	viewModeReferences
+	viewModeCallGraph
+	viewModeCompiler
+	viewModeAssembly
+	viewModeRun
+	viewModeModule
+)
+
+var viewModeItems = []basicwidget.DropdownListItem[viewMode]{
+	{Text: "AST", Value: viewModeAST},
+	{Text: "Documentation", Value: viewModeDocs},
+	{Text: "Formatted output", Value: viewModeFormatted},
+	{Text: "Rewrite rules", Value: viewModeRewrite},
+	{Text: "AST diff", Value: viewModeASTDiff},
+	{Text: "Version compare", Value: viewModeVersions},
+	{Text: "Scopes", Value: viewModeScopes},
+	{Text: "References", Value: viewModeReferences},
+	{Text: "Call graph", Value: viewModeCallGraph},
+	{Text: "Compiler notes", Value: viewModeCompiler},
+	{Text: "Assembly", Value: viewModeAssembly},
+	{Text: "Program output", Value: viewModeRun},
+	{Text: "Module and files", Value: viewModeModule},
+}
+
+// versionItems lists the selectable language versions; the empty value
+// stands for the go.mod go directive, or the toolchain version without one
+func versionItems() []basicwidget.DropdownListItem[string] {
+	items := []basicwidget.DropdownListItem[string]{
+		{Text: "Go version: go.mod default", Value: ""},
+	}
+	for _, v := range goVersions() {
+		items = append(items, basicwidget.DropdownListItem[string]{
+			Text:  "Go version: " + v,
+			Value: v,
+		})
+	}
+	return items
+}
+
 type RightPanel struct {
 	guigui.DefaultWidget
 
-	panel     basicwidget.Panel
-	titleText basicwidget.Text
-	treeList  basicwidget.List[int]
-	errorText basicwidget.Text
+	panel           basicwidget.Panel
+	titleText       basicwidget.Text
+	modeDropdown    basicwidget.DropdownList[viewMode]
+	versionDropdown basicwidget.DropdownList[string]
+	compareDropdown basicwidget.DropdownList[string]
+	breadcrumb      Breadcrumb
+	treeList        basicwidget.List[int]
+	errorText       basicwidget.Text
+	nodeEditor      NodeEditor
+	navigationBar   NavigationBar
+	inspector       Inspector
+	buildContextBar BuildContextBar
+	ruleEditor      RuleEditor
+	snapshotButton  basicwidget.Button
+	dotButton       basicwidget.Button
+	compilerButton  basicwidget.Button
+	assemblyButton  basicwidget.Button
+	ssaButton       basicwidget.Button
+	runButton       basicwidget.Button
+	testButton      basicwidget.Button
+	stopButton      basicwidget.Button
+	runner          Runner
+	compilerTask    Task
+	asmTask         Task
+
+	source         string
+	archive        *Archive
+	astNodes       []*ASTNode
+	docNodes       []*ASTNode
+	formattedNodes []*ASTNode
+	rewriteNodes   []*ASTNode
+	diffNodes      []*ASTNode
+	versionNodes   []*ASTNode
+	scopeNodes     []*ASTNode
+	referenceNodes []*ASTNode
+	callGraphNodes []*ASTNode
+	compilerNodes  []*ASTNode
+	assemblyNodes  []*ASTNode
+	runNodes       []*ASTNode
+	moduleNodes    []*ASTNode
+	listItems      []basicwidget.ListItem[int]
+	parseErr       error
+	mode           viewMode
+	selected       *ASTNode
+	rules          string
+	rewritten      string
+	snapshot       *Archive
+	goVersion      string
+	compareVersion string
+	typeInfo       *TypeInfo
+	callGraph      *CallGraph
+	callGraphErr   error
+	dotPath        string
+	compilerDiags  []Diagnostic
+	asmFuncs       []AsmFunc
+	asmErr         error
+	asmStatus      string
+	runVersion     int
+	testRows       map[*ASTNode]TestFunc
+	testResults    map[string]TestResult
+	testRun        *TestFunc
+	buildContext   BuildContext
+	sidePaneKey    sidePaneKey
+	sidePaneTitle  string
+	sidePaneNodes  []*ASTNode
+
+	onSourceEdited func(string)
+	onRevealSource func(start, end int)
+}
+
+// SetOnSourceEdited registers a callback receiving the source regenerated
+// after the AST has been edited from the tree
+func (r *RightPanel) SetOnSourceEdited(f func(string)) {
+	r.onSourceEdited = f
+}
+
+// SetOnRevealSource registers a callback receiving the range of the editor
+// contents that a navigation action jumped to
+func (r *RightPanel) SetOnRevealSource(f func(start, end int)) {
+	r.onRevealSource = f
+}
+
+// Mode returns the current view mode
+func (r *RightPanel) Mode() viewMode {
+	return r.mode
+}
 
-	source    string
-	astNodes  []*ASTNode
-	listItems []basicwidget.ListItem[int]
-	parseErr  error
+// SetMode switches the view mode
+func (r *RightPanel) SetMode(mode viewMode) {
+	r.mode = mode
 }
 
 func (r *RightPanel) SetSource(source string) {
@@ -30,33 +165,419 @@ func (r *RightPanel) SetSource(source string) {
 
 func (r *RightPanel) parseAST() {
 	if r.source == "" {
+		r.archive = nil
 		r.astNodes = nil
+		r.docNodes = nil
+		r.formattedNodes = nil
+		r.typeInfo = nil
+		r.callGraph = nil
 		r.parseErr = nil
 		return
 	}
 
-	nodes, err := ParseTxtar(r.source)
+	archive, err := ParseTxtar(r.source)
 	if err != nil {
 		r.parseErr = err
+		r.archive = nil
 		r.astNodes = nil
+		r.docNodes = nil
+		r.formattedNodes = nil
+		r.typeInfo = nil
+		r.callGraph = nil
 		return
 	}
 
+	// Keep rows that survive the edit expanded or collapsed as they were
+	collapsed, _ := r.TreeState()
+
 	r.parseErr = nil
-	r.astNodes = nodes
+	r.archive = archive
+	r.selected = nil
+	r.compilerDiags = nil
+	r.asmFuncs = nil
+	r.asmErr = nil
+	r.asmStatus = ""
+	r.compilerNodes = []*ASTNode{{
+		Label:       "Run the build to see escape analysis and inlining decisions",
+		IndentLevel: 1,
+	}}
+	r.referenceNodes = []*ASTNode{{
+		Label:       "Select an identifier and choose Find References",
+		IndentLevel: 1,
+	}}
+	r.showOutput()
+	r.astNodes = archive.Nodes
+	r.ensureBuildContext()
+	MarkExcludedFiles(archive, r.buildContext)
+	r.docNodes = DocNodes(archive)
+	r.formattedNodes = FormatNodes(archive)
+	r.moduleNodes = ModuleNodes(archive)
+	if r.rules != "" {
+		r.runRewrite()
+	}
+	r.diffSnapshot()
+	r.typeCheck()
+	r.RestoreTreeState(collapsed, "")
 }
 
-func (r *RightPanel) buildListItems() {
-	r.listItems = r.listItems[:0]
+// effectiveGoVersion resolves the default language version to the go.mod
+// go directive of the archive
+func (r *RightPanel) effectiveGoVersion(version string) string {
+	if version == "" && r.archive != nil {
+		return r.archive.GoVersion()
+	}
+	return version
+}
+
+// typeCheck type-checks the archive under the selected language version
+// and compares it with the second version
+func (r *RightPanel) typeCheck() {
+	if r.archive == nil {
+		return
+	}
+	r.typeInfo = r.archive.TypeCheck(r.effectiveGoVersion(r.goVersion))
+	r.annotate()
+	r.scopeNodes = ScopeNodes(r.archive, r.typeInfo)
+	r.callGraph, r.callGraphErr = BuildCallGraph(r.archive, r.typeInfo)
+	r.showCallGraph()
+	r.inspectSelected()
+	r.highlightSelected()
+	r.versionNodes = nil
+}
+
+// annotate replaces the annotation rows of the AST with those computed for
+// the current type information and compiler run
+func (r *RightPanel) annotate() {
+	ClearAnnotations(r.astNodes)
+	if r.typeInfo != nil {
+		AnnotateInstances(r.astNodes, r.typeInfo)
+		AnnotateMethodSets(r.astNodes, r.typeInfo)
+	}
+	AnnotateDiagnostics(r.archive, r.compilerDiags)
+	r.testRows = AnnotateTests(r.archive, r.archive.TestFuncs(), r.testResults)
+}
+
+// runCompiler builds the archive with escape analysis and inlining
+// diagnostics in the background and attaches them to the AST once the build
+// finishes
+func (r *RightPanel) runCompiler() {
+	if r.archive == nil {
+		return
+	}
+	a := r.archive
+	started := r.compilerTask.Start(func() func() {
+		diags, err := CompilerDiagnostics(a)
+		return func() {
+			// The source changed while building
+			if r.archive != a {
+				return
+			}
+			r.showCompilerDiagnostics(diags, err)
+		}
+	})
+	if !started {
+		r.nodeEditor.SetStatus("A build is already running")
+		return
+	}
+	r.compilerNodes = []*ASTNode{{
+		Label:       "Building with -gcflags=-m=2…",
+		IndentLevel: 1,
+	}}
+}
+
+// showCompilerDiagnostics lists the outcome of a compiler run and attaches
+// its notes to the AST
+func (r *RightPanel) showCompilerDiagnostics(diags []Diagnostic, err error) {
+	if err != nil {
+		r.compilerDiags = nil
+		r.compilerNodes = []*ASTNode{{
+			Label:       "Error: " + err.Error(),
+			IndentLevel: 1,
+		}}
+		r.annotate()
+		return
+	}
+	r.compilerDiags = diags
+	r.compilerNodes = DiagnosticNodes(r.archive, diags)
+	r.annotate()
+}
+
+// selectedFuncDecl returns the selected function declaration, or nil
+func (r *RightPanel) selectedFuncDecl() *ast.FuncDecl {
+	if r.selected == nil {
+		return nil
+	}
+	decl, _ := r.selected.Node.(*ast.FuncDecl)
+	return decl
+}
+
+// compileAssembly compiles the archive with -S in the background and shows
+// the assembly of the selected function once it is done
+func (r *RightPanel) compileAssembly() {
+	if r.archive == nil {
+		return
+	}
+	a := r.archive
+	r.startAssemblyTask("Compiling with -gcflags=-S…", func() func() {
+		funcs, err := CompileAssembly(a)
+		return func() {
+			if r.archive != a {
+				return
+			}
+			r.asmFuncs, r.asmErr = funcs, err
+			r.asmStatus = ""
+			r.showAssembly()
+		}
+	})
+}
 
-	if r.astNodes == nil {
+// dumpSSA writes the SSA phases of the selected function to an HTML file in
+// the background, compiling the archive first if needed
+func (r *RightPanel) dumpSSA() {
+	decl := r.selectedFuncDecl()
+	if r.archive == nil || decl == nil {
+		r.asmStatus = "Select a function declaration first"
+		r.showAssembly()
 		return
 	}
+	a := r.archive
+	funcs := r.asmFuncs
+	r.startAssemblyTask("Dumping the SSA phases of "+decl.Name.Name+"…", func() func() {
+		var err error
+		if funcs == nil {
+			funcs, err = CompileAssembly(a)
+		}
+		var status string
+		switch matched := a.asmFuncsOf(funcs, decl); {
+		case err != nil:
+		case len(matched) == 0:
+			status = "No code was generated for " + decl.Name.Name
+		default:
+			if path, err := DumpSSA(a, matched[0].Symbol); err != nil {
+				status = "Error: " + err.Error()
+			} else {
+				status = "SSA phases written to " + path
+			}
+		}
+		return func() {
+			if r.archive != a {
+				return
+			}
+			r.asmFuncs, r.asmErr = funcs, err
+			r.asmStatus = status
+			r.showAssembly()
+		}
+	})
+}
 
-	flatNodes := FlattenNodes(r.astNodes)
+// startAssemblyTask runs a build for the assembly view in the background,
+// showing status until it finishes
+func (r *RightPanel) startAssemblyTask(status string, work func() func()) {
+	if !r.asmTask.Start(work) {
+		r.asmStatus = "A build is already running"
+		r.showAssembly()
+		return
+	}
+	r.asmStatus = status
+	r.showAssembly()
+}
+
+// showAssembly shows the assembly of the selected function
+func (r *RightPanel) showAssembly() {
+	var nodes []*ASTNode
+	if r.asmStatus != "" {
+		nodes = append(nodes, &ASTNode{
+			Label:       r.asmStatus,
+			IndentLevel: 1,
+		})
+	}
+	switch {
+	case r.asmErr != nil:
+		nodes = append(nodes, &ASTNode{
+			Label:       "Error: " + r.asmErr.Error(),
+			IndentLevel: 1,
+		})
+	case r.asmFuncs == nil:
+		nodes = append(nodes, &ASTNode{
+			Label:       "Compile the archive to see the assembly of the selected function",
+			IndentLevel: 1,
+		})
+	default:
+		nodes = append(nodes, AssemblyNodes(r.archive, r.asmFuncs, r.selectedFuncDecl())...)
+	}
+	r.assemblyNodes = nodes
+}
+
+// startRun runs the go command on the archive and shows its output as it
+// is written
+func (r *RightPanel) startRun(args ...string) {
+	if r.archive == nil {
+		return
+	}
+	if err := r.runner.Start(r.archive, args...); err != nil {
+		r.runNodes = []*ASTNode{{
+			Label:       "Error: " + err.Error(),
+			IndentLevel: 1,
+		}}
+		return
+	}
+	r.showOutput()
+}
+
+// runMain runs the main package of the archive
+func (r *RightPanel) runMain() {
+	if r.archive == nil {
+		return
+	}
+	r.startRun("run", r.archive.mainPackage())
+}
+
+// runTest runs a single test and shows its outcome on its row once it
+// finishes
+func (r *RightPanel) runTest(t TestFunc) {
+	if err := r.runner.Start(r.archive, t.Args()...); err != nil {
+		r.nodeEditor.SetStatus("Error: " + err.Error())
+		return
+	}
+	if r.testResults == nil {
+		r.testResults = make(map[string]TestResult)
+	}
+	r.testResults[t.key()] = TestResult{Running: true}
+	r.testRun = &t
+	r.showOutput()
+	r.annotate()
+}
+
+// finishTest records the outcome of the test run by runTest
+func (r *RightPanel) finishTest() {
+	lines, _ := r.runner.Output()
+	r.testResults[r.testRun.key()] = parseTestOutput(*r.testRun, lines)
+	r.testRun = nil
+	if r.archive != nil {
+		r.annotate()
+	}
+}
+
+// showOutput shows the output of the last command run
+func (r *RightPanel) showOutput() {
+	r.runVersion = r.runner.Version()
+	lines, status := r.runner.Output()
+	if len(lines) == 0 {
+		r.runNodes = []*ASTNode{{
+			Label:       "Run the program or its tests to see their output",
+			IndentLevel: 1,
+		}}
+		return
+	}
+	r.runNodes = ConsoleNodes(r.archive, lines, status)
+}
+
+// takeSnapshot remembers the current source as the base of the AST diff
+func (r *RightPanel) takeSnapshot() {
+	if r.parseErr != nil || r.source == "" {
+		return
+	}
+	snapshot, err := ParseTxtar(r.source)
+	if err != nil {
+		return
+	}
+	r.snapshot = snapshot
+	r.diffSnapshot()
+}
+
+// diffSnapshot compares the snapshot with the current source
+func (r *RightPanel) diffSnapshot() {
+	if r.snapshot == nil {
+		r.diffNodes = []*ASTNode{{
+			Label:       "No snapshot yet: take one, edit the source and parse again",
+			IndentLevel: 1,
+		}}
+		return
+	}
+	r.diffNodes = DiffTrees(r.snapshot.Nodes, r.astNodes)
+}
+
+// runRewrite applies the current rewrite rules to the source
+func (r *RightPanel) runRewrite() {
+	r.rewriteNodes = nil
+	r.rewritten = ""
+
+	rules, err := ParseRewriteRules(r.rules)
+	if err != nil {
+		r.ruleEditor.SetStatus("Error: " + err.Error())
+		return
+	}
+	nodes, rewritten, err := RewriteNodes(r.source, rules)
+	if err != nil {
+		r.ruleEditor.SetStatus("Error: " + err.Error())
+		return
+	}
+	r.rewriteNodes = nodes
+	r.rewritten = rewritten
+	r.ruleEditor.SetStatus("")
+}
+
+// applyRewrite hands the rewritten source to the editor
+func (r *RightPanel) applyRewrite() {
+	if r.rewritten == "" {
+		r.ruleEditor.SetStatus("Run the rules first")
+		return
+	}
+	if r.onSourceEdited != nil {
+		r.onSourceEdited(r.rewritten)
+	}
+}
+
+// displayNodes returns the tree shown for the current view mode
+func (r *RightPanel) displayNodes() []*ASTNode {
+	switch r.mode {
+	case viewModeDocs:
+		return r.docNodes
+	case viewModeFormatted:
+		return r.formattedNodes
+	case viewModeRewrite:
+		return r.rewriteNodes
+	case viewModeASTDiff:
+		return r.diffNodes
+	case viewModeVersions:
+		r.ensureVersionNodes()
+		return r.versionNodes
+	case viewModeScopes:
+		return r.scopeNodes
+	case viewModeReferences:
+		return r.referenceNodes
+	case viewModeCallGraph:
+		return r.callGraphNodes
+	case viewModeCompiler:
+		return r.compilerNodes
+	case viewModeAssembly:
+		return r.assemblyNodes
+	case viewModeRun:
+		return r.runNodes
+	case viewModeModule:
+		return r.moduleNodes
+	default:
+		return r.astNodes
+	}
+}
+
+func (r *RightPanel) buildListItems() {
+	r.listItems = treeListItems(r.listItems[:0], r.displayNodes())
+}
+
+// treeListItems appends a list row for every visible node of a tree to items
+func treeListItems(items []basicwidget.ListItem[int], nodes []*ASTNode) []basicwidget.ListItem[int] {
+	flatNodes := FlattenNodes(nodes)
 	for i, node := range flatNodes {
 		hasChildren := len(node.Children) > 0
 		label := node.Label
+		textColor := node.Color
+		if node.Excluded {
+			label += " (excluded)"
+			if textColor == nil {
+				textColor = excludedFileColor
+			}
+		}
 		if hasChildren {
 			if node.Collapsed {
 				label = "[+] " + label
@@ -67,13 +588,15 @@ func (r *RightPanel) buildListItems() {
 			label = "    " + label
 		}
 
-		r.listItems = append(r.listItems, basicwidget.ListItem[int]{
+		items = append(items, basicwidget.ListItem[int]{
 			Text:        label,
+			TextColor:   textColor,
 			IndentLevel: node.IndentLevel,
 			Value:       i,
 			Collapsed:   node.Collapsed,
 		})
 	}
+	return items
 }
 
 func (r *RightPanel) toggleNodeCollapse(index int) {
@@ -81,7 +604,7 @@ func (r *RightPanel) toggleNodeCollapse(index int) {
 		return
 	}
 
-	flatNodes := FlattenNodes(r.astNodes)
+	flatNodes := FlattenNodes(r.displayNodes())
 	if index >= len(flatNodes) {
 		return
 	}
@@ -92,6 +615,280 @@ func (r *RightPanel) toggleNodeCollapse(index int) {
 	}
 }
 
+// selectNode handles a click on a row of the tree
+func (r *RightPanel) selectNode(index int) {
+	flatNodes := FlattenNodes(r.displayNodes())
+	if index < 0 || index >= len(flatNodes) {
+		return
+	}
+
+	if r.mode == viewModeAST {
+		if t, ok := r.testRows[flatNodes[index]]; ok {
+			r.runTest(t)
+			return
+		}
+		r.setSelected(flatNodes[index])
+		r.toggleNodeCollapse(index)
+		return
+	}
+
+	if n := flatNodes[index].Node; n != nil {
+		r.revealNode(n)
+		r.revealSource(n)
+		return
+	}
+	r.toggleNodeCollapse(index)
+}
+
+// revealSource selects the source of n in the editor
+func (r *RightPanel) revealSource(n ast.Node) {
+	if r.onRevealSource == nil {
+		return
+	}
+	start := r.archive.ContentOffset(r.source, n.Pos())
+	end := r.archive.ContentOffset(r.source, n.End())
+	if start >= 0 && end >= start {
+		r.onRevealSource(start, end)
+	}
+}
+
+// setSelected makes node the target of the editor, inspector and breadcrumb
+func (r *RightPanel) setSelected(node *ASTNode) {
+	r.selected = node
+	if node.Node != nil {
+		r.nodeEditor.SetStatus("Selected: " + nodeSummary(node.Node))
+	} else {
+		r.nodeEditor.SetStatus("Selected row has no syntax node")
+	}
+	r.breadcrumb.SetPath(node.Ancestors())
+	r.inspectSelected()
+	r.highlightSelected()
+	r.showCallGraph()
+	r.showAssembly()
+}
+
+// showCallGraph shows the callers and callees of the selected node
+func (r *RightPanel) showCallGraph() {
+	var nodes []*ASTNode
+	if r.dotPath != "" {
+		nodes = append(nodes, &ASTNode{
+			Label:       "DOT written to " + r.dotPath,
+			IndentLevel: 1,
+		})
+	}
+	switch {
+	case r.callGraphErr != nil:
+		nodes = append(nodes, &ASTNode{
+			Label:       "Error: " + r.callGraphErr.Error(),
+			IndentLevel: 1,
+		})
+	case r.callGraph != nil:
+		var selected ast.Node
+		if r.selected != nil {
+			selected = r.selected.Node
+		}
+		nodes = append(nodes, CallGraphNodes(r.archive, r.typeInfo, r.callGraph, selected)...)
+	}
+	r.callGraphNodes = nodes
+}
+
+// exportDOT writes the whole call graph to a temporary DOT file
+func (r *RightPanel) exportDOT() {
+	if r.callGraph == nil {
+		return
+	}
+	f, err := os.CreateTemp("", "callgraph-*.dot")
+	if err != nil {
+		r.callGraphErr = err
+		r.showCallGraph()
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(r.callGraph.DOT()); err != nil {
+		r.callGraphErr = err
+		r.showCallGraph()
+		return
+	}
+	r.dotPath = f.Name()
+	r.showCallGraph()
+}
+
+// highlightSelected highlights the declaration and uses of the object the
+// selected node refers to
+func (r *RightPanel) highlightSelected() {
+	if r.typeInfo == nil {
+		return
+	}
+	var obj types.Object
+	if r.selected != nil && r.selected.Node != nil {
+		obj = objectOf(r.typeInfo, r.selected.Node)
+	}
+	decls, uses := HighlightObject(r.astNodes, r.typeInfo, obj)
+	HighlightObject(r.scopeNodes, r.typeInfo, obj)
+	if obj != nil {
+		r.nodeEditor.SetStatus(fmt.Sprintf("Selected: %s (%s: %d declarations, %d uses)", nodeSummary(r.selected.Node), obj.Name(), decls, uses))
+	}
+}
+
+// ensureBuildContext defaults the build context to the host
+func (r *RightPanel) ensureBuildContext() {
+	if r.buildContext.GOOS == "" {
+		r.buildContext = defaultBuildContext()
+		r.buildContextBar.SetBuildContext(r.buildContext)
+	}
+}
+
+// ensureVersionNodes compares the type errors of the two language versions
+// the first time the version compare view is shown after a type check, as
+// it takes another full type check
+func (r *RightPanel) ensureVersionNodes() {
+	if r.versionNodes == nil && r.typeInfo != nil {
+		r.versionNodes = VersionCompareNodes(r.archive, r.typeInfo, r.effectiveGoVersion(r.compareVersion))
+	}
+}
+
+// revealNode switches to the AST view, expands the ancestors of the node
+// representing n and selects it
+func (r *RightPanel) revealNode(n ast.Node) {
+	path := FindPath(r.astNodes, n)
+	if path == nil {
+		return
+	}
+	r.selectTreeNode(path[len(path)-1])
+}
+
+// selectTreeNode switches to the AST view, expands the ancestors of target
+// and selects it
+func (r *RightPanel) selectTreeNode(target *ASTNode) {
+	for node := target.Parent; node != nil; node = node.Parent {
+		node.Collapsed = false
+	}
+
+	r.mode = viewModeAST
+	r.setSelected(target)
+	for i, node := range FlattenNodes(r.astNodes) {
+		if node == target {
+			r.buildListItems()
+			r.treeList.SetItems(r.listItems)
+			r.treeList.SelectItemByIndex(i)
+			break
+		}
+	}
+}
+
+// hasInspection reports whether the inspector has a node to show
+func (r *RightPanel) hasInspection() bool {
+	return r.archive != nil && r.selected != nil && r.selected.Node != nil
+}
+
+// inspectSelected shows the details of the selected node in the inspector
+func (r *RightPanel) inspectSelected() {
+	if !r.hasInspection() {
+		return
+	}
+	n := r.selected.Node
+	r.inspector.SetNodes(nodeSummary(n), InspectNodes(r.archive, r.typeInfo, n))
+}
+
+// navigate jumps to the definition of the object n refers to, or lists its
+// references
+func (r *RightPanel) navigate(nav navigation, n ast.Node) {
+	if r.archive == nil || r.typeInfo == nil || n == nil {
+		r.nodeEditor.SetStatus("Select an identifier first")
+		return
+	}
+	obj := objectOf(r.typeInfo, n)
+	if obj == nil {
+		r.nodeEditor.SetStatus("No object for " + nodeSummary(n))
+		return
+	}
+
+	switch nav {
+	case navigationDefinition:
+		if !r.archive.inArchive(obj.Pos()) {
+			r.nodeEditor.SetStatus(types.ObjectString(obj, packageName) + " is defined outside the archive")
+			return
+		}
+		if decl := r.archive.displayedNodeAt(obj.Pos()); decl != nil {
+			r.revealNode(decl)
+		}
+		if r.onRevealSource != nil {
+			if start := r.archive.ContentOffset(r.source, obj.Pos()); start >= 0 {
+				r.onRevealSource(start, start+len(obj.Name()))
+			}
+		}
+	case navigationReferences:
+		r.referenceNodes = ReferenceNodes(r.archive, r.typeInfo, obj)
+		r.mode = viewModeReferences
+	}
+}
+
+// NavigateSource runs a navigation action on the identifier at offset in
+// the editor contents, parsing them first if they changed
+func (r *RightPanel) NavigateSource(nav navigation, source string, offset int) {
+	if source != r.source {
+		r.SetSource(source)
+	}
+	if r.archive == nil {
+		return
+	}
+	id := r.archive.identAt(r.archive.PosAt(r.source, offset))
+	if id == nil {
+		r.nodeEditor.SetStatus("No identifier at the cursor")
+		return
+	}
+	r.navigate(nav, id)
+}
+
+// applyEdit performs an editing operation on the selected node and hands
+// the regenerated source to the editor. The edit is made on a fresh parse,
+// so that the displayed tree stays in sync with the editor if it fails.
+func (r *RightPanel) applyEdit(edit nodeEdit, value string) {
+	if r.archive == nil || r.selected == nil || r.selected.Node == nil {
+		r.nodeEditor.SetStatus("Select a node in the tree first")
+		return
+	}
+
+	work, err := ParseTxtar(r.source)
+	if err != nil {
+		r.nodeEditor.SetStatus("Error: " + err.Error())
+		return
+	}
+	n := work.counterpart(r.selected.Node)
+	if n == nil {
+		r.nodeEditor.SetStatus("Error: cannot edit " + nodeSummary(r.selected.Node))
+		return
+	}
+
+	switch edit {
+	case nodeEditRename:
+		err = work.Rename(n, value)
+	case nodeEditOperator:
+		err = work.SetOperator(n, value)
+	case nodeEditDelete:
+		err = work.Delete(n)
+	case nodeEditMoveUp:
+		err = work.MoveDecl(n, -1)
+	case nodeEditMoveDown:
+		err = work.MoveDecl(n, 1)
+	}
+	if err != nil {
+		r.nodeEditor.SetStatus("Error: " + err.Error())
+		return
+	}
+
+	source, err := work.Source()
+	if err != nil {
+		r.nodeEditor.SetStatus("Error: " + err.Error())
+		return
+	}
+	r.selected = nil
+	r.nodeEditor.SetStatus("Applied to " + nodeSummary(n))
+	if r.onSourceEdited != nil {
+		r.onSourceEdited(source)
+	}
+}
+
 func (r *RightPanel) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
 	adder.AddChild(&r.panel)
 	r.panel.SetContent(&rightPanelContent{rightPanel: r})
@@ -101,6 +898,25 @@ func (r *RightPanel) Build(context *guigui.Context, adder *guigui.ChildAdder) er
 	return nil
 }
 
+// Tick picks up output written by a running command and the results of
+// builds finished in the background
+func (r *RightPanel) Tick(context *guigui.Context, widgetBounds *guigui.WidgetBounds) error {
+	if r.runner.Version() != r.runVersion {
+		r.showOutput()
+		if r.testRun != nil && !r.runner.Running() {
+			r.finishTest()
+		}
+		guigui.RequestRebuild(r)
+	}
+	if r.compilerTask.Poll() {
+		guigui.RequestRebuild(r)
+	}
+	if r.asmTask.Poll() {
+		guigui.RequestRebuild(r)
+	}
+	return nil
+}
+
 func (r *RightPanel) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
 	layouter.LayoutWidget(&r.panel, widgetBounds.Bounds())
 }
@@ -112,27 +928,137 @@ type rightPanelContent struct {
 
 func (p *rightPanelContent) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
 	adder.AddChild(&p.rightPanel.titleText)
+	adder.AddChild(&p.rightPanel.modeDropdown)
 
 	p.rightPanel.titleText.SetValue("AST Tree:")
 	p.rightPanel.titleText.SetBold(true)
 
+	p.rightPanel.modeDropdown.SetItems(viewModeItems)
+	p.rightPanel.modeDropdown.SelectItemByValue(p.rightPanel.mode)
+	p.rightPanel.modeDropdown.SetOnItemSelected(func(index int) {
+		p.rightPanel.mode = viewModeItems[index].Value
+	})
+
+	adder.AddChild(&p.rightPanel.versionDropdown)
+	versions := versionItems()
+	p.rightPanel.versionDropdown.SetItems(versions)
+	p.rightPanel.versionDropdown.SelectItemByValue(p.rightPanel.goVersion)
+	p.rightPanel.versionDropdown.SetOnItemSelected(func(index int) {
+		p.rightPanel.goVersion = versions[index].Value
+		p.rightPanel.typeCheck()
+	})
+
 	if p.rightPanel.parseErr != nil {
 		adder.AddChild(&p.rightPanel.errorText)
 		p.rightPanel.errorText.SetValue("Error: " + p.rightPanel.parseErr.Error())
 	} else {
+		if p.rightPanel.mode == viewModeAST && p.rightPanel.selected != nil {
+			adder.AddChild(&p.rightPanel.breadcrumb)
+			p.rightPanel.breadcrumb.SetOnSelected(func(node *ASTNode) {
+				p.rightPanel.selectTreeNode(node)
+			})
+		}
 		adder.AddChild(&p.rightPanel.treeList)
 		p.rightPanel.buildListItems()
 		p.rightPanel.treeList.SetItems(p.rightPanel.listItems)
 		p.rightPanel.treeList.SetStripeVisible(true)
 		p.rightPanel.treeList.SetOnItemSelected(func(index int) {
-			p.rightPanel.toggleNodeCollapse(index)
+			p.rightPanel.selectNode(index)
 		})
 		p.rightPanel.treeList.SetOnItemExpanderToggled(func(index int, expanded bool) {
-			flatNodes := FlattenNodes(p.rightPanel.astNodes)
+			flatNodes := FlattenNodes(p.rightPanel.displayNodes())
 			if index < len(flatNodes) {
 				flatNodes[index].Collapsed = !expanded
 			}
 		})
+
+		switch p.rightPanel.mode {
+		case viewModeAST:
+			adder.AddChild(&p.rightPanel.buildContextBar)
+			p.rightPanel.ensureBuildContext()
+			p.rightPanel.buildContextBar.SetOnChanged(func(bc BuildContext) {
+				p.rightPanel.buildContext = bc
+				MarkExcludedFiles(p.rightPanel.archive, bc)
+			})
+			if p.rightPanel.hasInspection() {
+				adder.AddChild(&p.rightPanel.inspector)
+				p.rightPanel.inspector.SetOnNodeSelected(func(n ast.Node) {
+					p.rightPanel.revealNode(n)
+				})
+			}
+			if p.rightPanel.hasInspection() {
+				adder.AddChild(&p.rightPanel.navigationBar)
+				p.rightPanel.navigationBar.SetOnNavigate(func(nav navigation) {
+					p.rightPanel.navigate(nav, p.rightPanel.selected.Node)
+				})
+			}
+			adder.AddChild(&p.rightPanel.nodeEditor)
+			p.rightPanel.nodeEditor.SetOnEdit(func(edit nodeEdit, value string) {
+				p.rightPanel.applyEdit(edit, value)
+			})
+		case viewModeRewrite:
+			adder.AddChild(&p.rightPanel.ruleEditor)
+			p.rightPanel.ruleEditor.SetOnRun(func(rules string) {
+				p.rightPanel.rules = rules
+				p.rightPanel.runRewrite()
+			})
+			p.rightPanel.ruleEditor.SetOnApply(func() {
+				p.rightPanel.applyRewrite()
+			})
+		case viewModeVersions:
+			adder.AddChild(&p.rightPanel.compareDropdown)
+			p.rightPanel.compareDropdown.SetItems(versions)
+			p.rightPanel.compareDropdown.SelectItemByValue(p.rightPanel.compareVersion)
+			p.rightPanel.compareDropdown.SetOnItemSelected(func(index int) {
+				p.rightPanel.compareVersion = versions[index].Value
+				p.rightPanel.versionNodes = nil
+			})
+		case viewModeCompiler:
+			adder.AddChild(&p.rightPanel.compilerButton)
+			p.rightPanel.compilerButton.SetText("Run go build -gcflags=-m=2")
+			p.rightPanel.compilerButton.SetOnDown(func() {
+				p.rightPanel.runCompiler()
+			})
+		case viewModeAssembly:
+			adder.AddChild(&p.rightPanel.assemblyButton)
+			p.rightPanel.assemblyButton.SetText("Compile with -gcflags=-S")
+			p.rightPanel.assemblyButton.SetOnDown(func() {
+				p.rightPanel.compileAssembly()
+			})
+			adder.AddChild(&p.rightPanel.ssaButton)
+			p.rightPanel.ssaButton.SetText("Dump SSA Phases (GOSSAFUNC)")
+			p.rightPanel.ssaButton.SetOnDown(func() {
+				p.rightPanel.dumpSSA()
+			})
+		case viewModeRun:
+			adder.AddChild(&p.rightPanel.runButton)
+			p.rightPanel.runButton.SetText("go run")
+			p.rightPanel.runButton.SetOnDown(func() {
+				p.rightPanel.runMain()
+			})
+			adder.AddChild(&p.rightPanel.testButton)
+			p.rightPanel.testButton.SetText("go test")
+			p.rightPanel.testButton.SetOnDown(func() {
+				p.rightPanel.startRun("test", "./...")
+			})
+			adder.AddChild(&p.rightPanel.stopButton)
+			p.rightPanel.stopButton.SetText("Stop")
+			p.rightPanel.stopButton.SetOnDown(func() {
+				p.rightPanel.runner.Stop()
+			})
+		case viewModeCallGraph:
+			adder.AddChild(&p.rightPanel.dotButton)
+			p.rightPanel.dotButton.SetText("Export DOT")
+			p.rightPanel.dotButton.SetOnDown(func() {
+				p.rightPanel.exportDOT()
+			})
+		case viewModeASTDiff:
+			adder.AddChild(&p.rightPanel.snapshotButton)
+			p.rightPanel.snapshotButton.SetText("Take Snapshot")
+			p.rightPanel.snapshotButton.SetOnDown(func() {
+				p.rightPanel.takeSnapshot()
+			})
+		}
 	}
 
 	return nil
@@ -149,18 +1075,89 @@ func (p *rightPanelContent) Layout(context *guigui.Context, widgetBounds *guigui
 		contentWidget = &p.rightPanel.treeList
 	}
 
+	items := []guigui.LinearLayoutItem{
+		{
+			Widget: &p.rightPanel.titleText,
+		},
+		{
+			Widget: &p.rightPanel.modeDropdown,
+		},
+		{
+			Widget: &p.rightPanel.versionDropdown,
+		},
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeRewrite {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.ruleEditor,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeVersions {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.compareDropdown,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeASTDiff {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.snapshotButton,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeCompiler {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.compilerButton,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeAssembly {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.assemblyButton,
+		}, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.ssaButton,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeRun {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.runButton,
+		}, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.testButton,
+		}, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.stopButton,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeCallGraph {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.dotButton,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeAST {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.buildContextBar,
+		})
+	}
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeAST && p.rightPanel.selected != nil {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.breadcrumb,
+		})
+	}
+	items = append(items, guigui.LinearLayoutItem{
+		Widget: contentWidget,
+		Size:   guigui.FlexibleSize(1),
+	})
+	if p.rightPanel.parseErr == nil && p.rightPanel.mode == viewModeAST {
+		if p.rightPanel.hasInspection() {
+			items = append(items, guigui.LinearLayoutItem{
+				Widget: &p.rightPanel.inspector,
+			}, guigui.LinearLayoutItem{
+				Widget: &p.rightPanel.navigationBar,
+			})
+		}
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &p.rightPanel.nodeEditor,
+		})
+	}
+
 	(guigui.LinearLayout{
 		Direction: guigui.LayoutDirectionVertical,
-		Items: []guigui.LinearLayoutItem{
-			{
-				Widget: &p.rightPanel.titleText,
-			},
-			{
-				Widget: contentWidget,
-				Size:   guigui.FlexibleSize(1),
-			},
-		},
-		Gap: u / 2,
+		Items:     items,
+		Gap:       u / 2,
 		Padding: guigui.Padding{
 			Start:  u / 2,
 			Top:    u / 2,
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+const defaultRules = `// pattern -> replacement; single lowercase letters are wildcards
+fmt.Sprintf("%s", x) -> fmt.Sprint(x)
+`
+
+// RuleEditor lets the user write rewrite rules and run them
+type RuleEditor struct {
+	guigui.DefaultWidget
+
+	rulesInput  basicwidget.TextInput
+	runButton   basicwidget.Button
+	applyButton basicwidget.Button
+	statusText  basicwidget.Text
+
+	rules       string
+	status      string
+	initialized bool
+	onRun       func(rules string)
+	onApply     func()
+}
+
+func (e *RuleEditor) SetOnRun(f func(rules string)) {
+	e.onRun = f
+}
+
+func (e *RuleEditor) SetOnApply(f func()) {
+	e.onApply = f
+}
+
+// SetStatus shows a message about the last run
+func (e *RuleEditor) SetStatus(status string) {
+	e.status = status
+}
+
+func (e *RuleEditor) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	adder.AddChild(&e.rulesInput)
+	adder.AddChild(&e.runButton)
+	adder.AddChild(&e.applyButton)
+	adder.AddChild(&e.statusText)
+
+	e.rulesInput.SetMultiline(true)
+	e.rulesInput.SetAutoWrap(false)
+	e.rulesInput.SetVerticalAlign(basicwidget.VerticalAlignTop)
+	e.rulesInput.SetHorizontalAlign(basicwidget.HorizontalAlignStart)
+
+	// Initialize with the sample rules only once
+	if !e.initialized {
+		e.initialized = true
+		e.rules = defaultRules
+		e.rulesInput.SetValue(defaultRules)
+	}
+
+	e.rulesInput.SetOnValueChanged(func(text string, committed bool) {
+		e.rules = text
+	})
+
+	e.runButton.SetText("Run Rules")
+	e.runButton.SetOnDown(func() {
+		if e.onRun != nil {
+			e.onRun(e.rules)
+		}
+	})
+	e.applyButton.SetText("Apply to Editor")
+	e.applyButton.SetOnDown(func() {
+		if e.onApply != nil {
+			e.onApply()
+		}
+	})
+
+	e.statusText.SetValue(e.status)
+
+	return nil
+}
+
+func (e *RuleEditor) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+	bounds := widgetBounds.Bounds()
+
+	rowHeight := e.runButton.Measure(context, guigui.Constraints{}).Y
+
+	inputBounds := image.Rectangle{
+		Min: bounds.Min,
+		Max: image.Pt(bounds.Max.X, bounds.Min.Y+4*u),
+	}
+	layouter.LayoutWidget(&e.rulesInput, inputBounds)
+
+	row := image.Rectangle{
+		Min: image.Pt(bounds.Min.X, inputBounds.Max.Y+u/4),
+		Max: image.Pt(bounds.Max.X, inputBounds.Max.Y+u/4+rowHeight),
+	}
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &e.runButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+			{
+				Widget: &e.applyButton,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 4,
+	}).LayoutWidgets(context, row, layouter)
+
+	statusBounds := image.Rectangle{
+		Min: image.Pt(bounds.Min.X, row.Max.Y+u/4),
+		Max: bounds.Max,
+	}
+	layouter.LayoutWidget(&e.statusText, statusBounds)
+}
+
+func (e *RuleEditor) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	rowHeight := e.runButton.Measure(context, guigui.Constraints{}).Y
+	h := 4*u + u/4 + rowHeight + u/4 + u
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
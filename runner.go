@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runTimeout bounds how long a program or its tests may run
+const runTimeout = 30 * time.Second
+
+// waitDelay bounds how long a killed command may keep its output open
+const waitDelay = 2 * time.Second
+
+// maxConsoleLines is the number of output lines kept; older ones are dropped
+const maxConsoleLines = 5000
+
+var stderrColor = color.RGBA{R: 0xcf, G: 0x22, B: 0x2e, A: 0xff}
+
+// ConsoleLine is a line a command wrote to stdout or stderr
+type ConsoleLine struct {
+	Text   string
+	Stderr bool
+}
+
+// Runner runs the go command on an archive in the background and collects
+// its output as it is written. It is safe for concurrent use.
+type Runner struct {
+	mu      sync.Mutex
+	lines   []ConsoleLine
+	partial [2]string
+	status  string
+	running bool
+	stopped bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	version int
+}
+
+// Start runs the go command with args in a workspace holding the archive.
+// It returns once the command has started.
+func (r *Runner) Start(a *Archive, args ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return errors.New("a command is already running")
+	}
+
+	w, err := a.NewWorkspace()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	cmd := w.Command(ctx, args...)
+	cmd.Stdout = runnerStream{runner: r}
+	cmd.Stderr = runnerStream{runner: r, stderr: true}
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+
+	title := "go " + strings.Join(args, " ")
+	r.lines = []ConsoleLine{{Text: "$ " + title}}
+	r.partial = [2]string{}
+	r.stopped = false
+	r.version++
+	if err := cmd.Start(); err != nil {
+		cancel()
+		w.Close()
+		r.status = "Error: " + err.Error()
+		return err
+	}
+	r.running = true
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.status = "Running " + title
+
+	start := time.Now()
+	done := r.done
+	go func() {
+		defer close(done)
+		err := cmd.Wait()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		cancel()
+		w.Close()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.flush()
+		switch {
+		case r.stopped:
+			r.status = fmt.Sprintf("Stopped after %s", elapsed)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			r.status = fmt.Sprintf("Killed after the %s timeout", runTimeout)
+		case err != nil:
+			r.status = fmt.Sprintf("Failed after %s: %v", elapsed, err)
+		default:
+			r.status = fmt.Sprintf("Finished in %s", elapsed)
+		}
+		r.running = false
+		r.cancel = nil
+		r.version++
+	}()
+	return nil
+}
+
+// Stop kills the running command, if any
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.stopped = true
+		r.cancel()
+	}
+}
+
+// Wait blocks until the last command started has exited and its workspace
+// has been removed
+func (r *Runner) Wait() {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Running reports whether a command is running
+func (r *Runner) Running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// Version returns a counter that changes whenever the output or status do
+func (r *Runner) Version() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.version
+}
+
+// Output returns a copy of the lines written so far and the status of the
+// command
+func (r *Runner) Output() ([]ConsoleLine, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ConsoleLine(nil), r.lines...), r.status
+}
+
+// write splits output into lines, keeping an unterminated last line until
+// more output arrives. r.mu must be held.
+func (r *Runner) write(p []byte, stderr bool) {
+	i := 0
+	if stderr {
+		i = 1
+	}
+	text := r.partial[i] + string(p)
+	for {
+		line, rest, ok := strings.Cut(text, "\n")
+		if !ok {
+			break
+		}
+		r.lines = append(r.lines, ConsoleLine{
+			Text:   strings.TrimSuffix(line, "\r"),
+			Stderr: stderr,
+		})
+		text = rest
+	}
+	r.partial[i] = text
+	if n := len(r.lines) - maxConsoleLines; n > 0 {
+		r.lines = r.lines[n:]
+	}
+	r.version++
+}
+
+// flush emits unterminated last lines. r.mu must be held.
+func (r *Runner) flush() {
+	for i, text := range r.partial {
+		if text != "" {
+			r.lines = append(r.lines, ConsoleLine{
+				Text:   text,
+				Stderr: i == 1,
+			})
+		}
+	}
+	r.partial = [2]string{}
+}
+
+// runnerStream is the stdout or stderr of a command started by a Runner
+type runnerStream struct {
+	runner *Runner
+	stderr bool
+}
+
+func (s runnerStream) Write(p []byte) (int, error) {
+	s.runner.mu.Lock()
+	defer s.runner.mu.Unlock()
+	s.runner.write(p, s.stderr)
+	return len(p), nil
+}
+
+// mainPackage returns the go run target of the first main package in the
+// archive, or "." if there is none
+func (a *Archive) mainPackage() string {
+	for _, f := range a.Files {
+		name := a.Fset.File(f.Pos()).Name()
+		if f.Name.Name == "main" && !strings.HasSuffix(name, "_test.go") {
+			return goPackagePath(name)
+		}
+	}
+	return "."
+}
+
+// goPackagePath returns the relative package path of the directory holding
+// an archive file, as accepted by the go command
+func goPackagePath(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return "./" + dir
+	}
+	return "."
+}
+
+// ConsoleNodes shows the status and output of a command. Lines starting
+// with a position in the archive are linked to the node there.
+func ConsoleNodes(a *Archive, lines []ConsoleLine, status string) []*ASTNode {
+	var nodes []*ASTNode
+	if status != "" {
+		nodes = append(nodes, &ASTNode{
+			Label:       status,
+			IndentLevel: 1,
+		})
+	}
+	for _, line := range lines {
+		node := &ASTNode{
+			Label:       line.Text,
+			IndentLevel: 1,
+		}
+		if line.Stderr {
+			node.Color = stderrColor
+		}
+		if a != nil {
+			if d, ok := parseDiagnosticLine(strings.TrimSpace(line.Text)); ok {
+				node.Node = a.displayedNodeAt(a.diagnosticPos(d))
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
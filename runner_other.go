@@ -0,0 +1,11 @@
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// killProcessGroup leaves cmd as is; only the go command itself is killed
+// when it is cancelled, and WaitDelay closes the pipes its children keep open
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"image/color"
+	"strings"
+)
+
+var (
+	declHighlightColor = color.RGBA{R: 0x82, G: 0x50, B: 0xdf, A: 0xff}
+	useHighlightColor  = color.RGBA{R: 0x09, G: 0x69, B: 0xda, A: 0xff}
+)
+
+// ScopeNodes shows the scope tree of the type-checked archive, from the
+// universe down to block scopes, with the objects declared in each scope
+func ScopeNodes(a *Archive, ti *TypeInfo) []*ASTNode {
+	scopeNodes := make(map[*types.Scope]ast.Node)
+	for n, scope := range ti.Info.Scopes {
+		scopeNodes[scope] = n
+	}
+
+	universe := scopeToNode(a, types.Universe, scopeNodes, 1)
+	universe.Label = fmt.Sprintf("Universe scope (%d objects)", types.Universe.Len())
+	universe.Collapsed = true
+
+	nodes := []*ASTNode{universe}
+	for _, pkg := range ti.Packages {
+		if pkg == nil {
+			continue
+		}
+		node := scopeToNode(a, pkg.Scope(), scopeNodes, 1)
+		node.Label = fmt.Sprintf("Package scope: %s (%d objects)", pkg.Name(), pkg.Scope().Len())
+		nodes = append(nodes, node)
+	}
+	LinkParents(nodes, nil)
+	return nodes
+}
+
+// scopeToNode lists the objects declared in a scope followed by its child
+// scopes
+func scopeToNode(a *Archive, scope *types.Scope, scopeNodes map[*types.Scope]ast.Node, level int) *ASTNode {
+	node := &ASTNode{
+		IndentLevel: level,
+	}
+	if n, ok := scopeNodes[scope]; ok {
+		node.Label = fmt.Sprintf("%s (%d objects)", scopeLabel(a, n), scope.Len())
+		node.Node = a.displayedNodeAt(n.Pos())
+	}
+
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		label := types.ObjectString(obj, packageName)
+		if obj.Pos().IsValid() {
+			label += " (" + positionString(a, obj.Pos()) + ")"
+		}
+		objNode := &ASTNode{
+			Label:       label,
+			IndentLevel: level + 1,
+		}
+		if obj.Pos().IsValid() {
+			objNode.Node = a.displayedNodeAt(obj.Pos())
+		}
+		node.Children = append(node.Children, objNode)
+	}
+
+	for i := 0; i < scope.NumChildren(); i++ {
+		node.Children = append(node.Children, scopeToNode(a, scope.Child(i), scopeNodes, level+1))
+	}
+	return node
+}
+
+// scopeLabel names the scope introduced by a syntax node
+func scopeLabel(a *Archive, n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.File:
+		return "File scope: " + a.Fset.Position(n.Pos()).Filename
+	case *ast.TypeSpec:
+		return "Type parameter scope: " + n.Name.Name
+	case *ast.FuncType:
+		line := a.Fset.Position(n.Pos()).Line
+		for _, parent := range a.enclosingNodes(n.Pos()) {
+			switch parent := parent.(type) {
+			case *ast.FuncDecl:
+				if parent.Type == n {
+					return "Function scope: " + parent.Name.Name
+				}
+			case *ast.FuncLit:
+				if parent.Type == n {
+					return fmt.Sprintf("Function scope: func literal (line %d)", line)
+				}
+			}
+		}
+		return fmt.Sprintf("Function scope: func type (line %d)", line)
+	}
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+	return fmt.Sprintf("Block scope: %s (line %d)", kind, a.Fset.Position(n.Pos()).Line)
+}
+
+// HighlightObject colors the rows that declare obj and the rows that use
+// it, clearing any previous highlight, and returns how many of each it
+// found. A nil obj only clears. Annotation rows keep their own color.
+func HighlightObject(nodes []*ASTNode, ti *TypeInfo, obj types.Object) (decls, uses int) {
+	for _, node := range nodes {
+		if node.Annotation {
+			continue
+		}
+		node.Color = nil
+		if obj != nil && node.Node != nil && originObject(objectOf(ti, node.Node)) == originObject(obj) {
+			if isDeclaration(ti, node.Node) {
+				node.Color = declHighlightColor
+				decls++
+			} else {
+				node.Color = useHighlightColor
+				uses++
+			}
+		}
+		d, u := HighlightObject(node.Children, ti, obj)
+		decls += d
+		uses += u
+	}
+	return decls, uses
+}
+
+// originObject returns the generic method or field that obj instantiates,
+// so that uses through an instance such as List[int] refer to the same
+// object as the declaration
+func originObject(obj types.Object) types.Object {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		return o.Origin()
+	}
+	return obj
+}
+
+// isDeclaration reports whether n declares the object it refers to
+func isDeclaration(ti *TypeInfo, n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Ident:
+		_, ok := ti.Info.Defs[n]
+		return ok
+	case *ast.FuncDecl, *ast.TypeSpec, *ast.ImportSpec:
+		return true
+	}
+	return false
+}
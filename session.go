@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sessionState is what is kept between runs of the viewer
+type sessionState struct {
+	Current    int          `json:"current"`
+	Tabs       []tabState   `json:"tabs"`
+	SplitRatio float64      `json:"splitRatio,omitempty"`
+	SideRatio  float64      `json:"sideRatio,omitempty"`
+	Vertical   bool         `json:"vertical,omitempty"`
+	SidePane   sidePaneKind `json:"sidePane,omitempty"`
+	Window     [2]int       `json:"window,omitempty"`
+}
+
+// tabState is what is kept of a tab
+type tabState struct {
+	Name   string   `json:"name"`
+	Source string   `json:"source"`
+	Mode   viewMode `json:"mode"`
+
+	// Collapsed holds the collapse state of the AST rows that have
+	// children, keyed by their path
+	Collapsed map[string]bool `json:"collapsed,omitempty"`
+	Selected  string          `json:"selected,omitempty"`
+}
+
+// nodePathSep separates the labels of a node path
+const nodePathSep = " › "
+
+// nodePaths returns a key for every row of a tree, made of the keys of the
+// rows from the top level down to it, so that the row can be found again
+// after the source is parsed anew. Rows with the same key under one parent
+// are told apart by a number. Annotation rows are left out.
+func nodePaths(fset *token.FileSet, nodes []*ASTNode) map[*ASTNode]string {
+	paths := make(map[*ASTNode]string)
+	var walk func(nodes []*ASTNode, prefix string)
+	walk = func(nodes []*ASTNode, prefix string) {
+		seen := make(map[string]int)
+		for _, node := range nodes {
+			if node.Annotation {
+				continue
+			}
+			key := nodeKey(fset, node)
+			path := prefix + key
+			if n := seen[key]; n > 0 {
+				path += fmt.Sprintf(" #%d", n+1)
+			}
+			seen[key]++
+			paths[node] = path
+			walk(node.Children, path+nodePathSep)
+		}
+	}
+	walk(nodes, "")
+	return paths
+}
+
+// nodeKey names a row by its kind and, for files and declarations, by what
+// they declare. The rest of a label is left out, as counts and positions in
+// it change with every edit.
+func nodeKey(fset *token.FileSet, node *ASTNode) string {
+	kind, _, _ := strings.Cut(node.Label, " ")
+	kind = strings.TrimSuffix(kind, ":")
+	// Literal rows are labelled with their value
+	if r, _ := utf8.DecodeRuneInString(kind); !unicode.IsUpper(r) && node.Node != nil {
+		kind = strings.TrimPrefix(fmt.Sprintf("%T", node.Node), "*ast.")
+	}
+	if name := declaredName(fset, node.Node); name != "" {
+		return kind + " " + name
+	}
+	return kind
+}
+
+// declaredName returns the name of a file or of what a declaration, spec or
+// field declares, or "" for other nodes
+func declaredName(fset *token.FileSet, n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.File:
+		if fset != nil && fset.File(n.Pos()) != nil {
+			return fset.File(n.Pos()).Name()
+		}
+	case *ast.FuncDecl:
+		if n.Recv != nil && len(n.Recv.List) > 0 {
+			return exprString(n.Recv.List[0].Type) + "." + n.Name.Name
+		}
+		return n.Name.Name
+	case *ast.GenDecl:
+		if len(n.Specs) > 0 {
+			return declaredName(fset, n.Specs[0])
+		}
+	case *ast.TypeSpec:
+		return n.Name.Name
+	case *ast.ValueSpec:
+		return identNames(n.Names)
+	case *ast.ImportSpec:
+		return n.Path.Value
+	case *ast.Field:
+		return identNames(n.Names)
+	case *ast.Ident:
+		return n.Name
+	}
+	return ""
+}
+
+// TreeState returns the collapse state of the AST rows with children and
+// the path of the selected row
+func (r *RightPanel) TreeState() (collapsed map[string]bool, selected string) {
+	collapsed = make(map[string]bool)
+	for node, path := range nodePaths(r.fset(), r.astNodes) {
+		if len(node.Children) > 0 {
+			collapsed[path] = node.Collapsed
+		}
+		if node == r.selected {
+			selected = path
+		}
+	}
+	return collapsed, selected
+}
+
+// RestoreTreeState applies a state returned by TreeState to the rows that
+// still exist
+func (r *RightPanel) RestoreTreeState(collapsed map[string]bool, selected string) {
+	for node, path := range nodePaths(r.fset(), r.astNodes) {
+		if c, ok := collapsed[path]; ok {
+			node.Collapsed = c
+		}
+		if selected != "" && path == selected {
+			r.setSelected(node)
+		}
+	}
+}
+
+// fset returns the file set of the archive, or nil if there is none
+func (r *RightPanel) fset() *token.FileSet {
+	if r.archive == nil {
+		return nil
+	}
+	return r.archive.Fset
+}
+
+// sessionPath returns the file the session is kept in, under the user
+// configuration directory
+func sessionPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goastviewer", "session.json"), nil
+}
+
+// loadSession restores the tabs, tree state and layout of the previous run,
+// or opens a single tab with the sample source if there are none
+func (r *Root) loadSession() {
+	r.tabs = nil
+	r.current = 0
+
+	var state sessionState
+	if path, err := sessionPath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(data, &state); err != nil {
+				fmt.Fprintf(os.Stderr, "ignoring session %s: %v\n", path, err)
+				state = sessionState{}
+			}
+		}
+	}
+
+	for _, ts := range state.Tabs {
+		t := &Tab{Name: ts.Name}
+		t.SetSource(ts.Source)
+		t.rightPanel.SetMode(ts.Mode)
+		t.rightPanel.RestoreTreeState(ts.Collapsed, ts.Selected)
+		r.tabs = append(r.tabs, t)
+	}
+	if len(r.tabs) == 0 {
+		t := &Tab{Name: r.newTabName()}
+		t.SetSource(defaultSource)
+		r.tabs = append(r.tabs, t)
+	}
+	if state.Current >= 0 && state.Current < len(r.tabs) {
+		r.current = state.Current
+	}
+	if state.SplitRatio > 0 && state.SplitRatio < 1 {
+		r.splitRatio = state.SplitRatio
+	}
+	if state.SideRatio > 0 && state.SideRatio < 1 {
+		r.sideRatio = state.SideRatio
+	}
+	r.vertical = state.Vertical
+	if state.SidePane >= sidePaneNone && state.SidePane <= sidePaneDiagnostics {
+		r.sidePane = state.SidePane
+	}
+	if state.Window[0] > 0 && state.Window[1] > 0 {
+		r.windowSize = image.Pt(state.Window[0], state.Window[1])
+	}
+}
+
+// saveSession writes the tabs to the session file. Failures are reported
+// but otherwise ignored.
+func (r *Root) saveSession() {
+	state := sessionState{
+		Current:    r.current,
+		SplitRatio: r.splitRatio,
+		SideRatio:  r.sideRatio,
+		Vertical:   r.vertical,
+		SidePane:   r.sidePane,
+		Window:     [2]int{r.windowSize.X, r.windowSize.Y},
+	}
+	for _, t := range r.tabs {
+		collapsed, selected := t.rightPanel.TreeState()
+		state.Tabs = append(state.Tabs, tabState{
+			Name:      t.Name,
+			Source:    t.leftPanel.Source(),
+			Mode:      t.rightPanel.Mode(),
+			Collapsed: collapsed,
+			Selected:  selected,
+		})
+	}
+
+	if err := writeSession(state); err != nil {
+		fmt.Fprintf(os.Stderr, "saving session: %v\n", err)
+	}
+}
+
+func writeSession(state sessionState) error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
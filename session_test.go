@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+)
+
+func TestNodePathsSurviveEdits(t *testing.T) {
+	before, err := ParseTxtar(`-- a.go --
+package a
+
+//go:generate echo one
+
+func f() {}
+
+func g(x int) {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseTxtar(`-- a.go --
+package a
+
+//go:generate echo one
+//go:generate echo two
+
+func f() {
+	println()
+}
+
+func h() {}
+
+func g(x, y int) {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rowPath returns the path of the first row whose node matches
+	rowPath := func(a *Archive, match func(*ASTNode) bool) string {
+		t.Helper()
+		for node, path := range nodePaths(a.Fset, a.Nodes) {
+			if match(node) {
+				return path
+			}
+		}
+		t.Fatal("no matching row")
+		return ""
+	}
+	isFunc := func(name string) func(*ASTNode) bool {
+		return func(node *ASTNode) bool {
+			fd, ok := node.Node.(*ast.FuncDecl)
+			return ok && fd.Name.Name == name
+		}
+	}
+	isDirectives := func(node *ASTNode) bool {
+		return strings.HasPrefix(node.Label, "Directives")
+	}
+
+	for name, match := range map[string]func(*ASTNode) bool{
+		"file":       func(node *ASTNode) bool { _, ok := node.Node.(*ast.File); return ok },
+		"directives": isDirectives,
+		"f":          isFunc("f"),
+		"g":          isFunc("g"),
+	} {
+		if p, q := rowPath(before, match), rowPath(after, match); p != q {
+			t.Errorf("%s: path %q became %q", name, p, q)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/types"
+	"image"
+	"slices"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// sidePaneKind selects what the optional third pane displays
+type sidePaneKind int
+
+const (
+	sidePaneNone sidePaneKind = iota
+	sidePaneInspector
+	sidePaneTokens
+	sidePaneDiagnostics
+)
+
+var sidePaneItems = []basicwidget.DropdownListItem[sidePaneKind]{
+	{Text: "Third pane: none", Value: sidePaneNone},
+	{Text: "Third pane: Inspector", Value: sidePaneInspector},
+	{Text: "Third pane: Tokens", Value: sidePaneTokens},
+	{Text: "Third pane: Diagnostics", Value: sidePaneDiagnostics},
+}
+
+// SidePane is the optional third pane next to the editor and the tree
+type SidePane struct {
+	guigui.DefaultWidget
+
+	titleText basicwidget.Text
+	list      basicwidget.List[int]
+
+	title          string
+	nodes          []*ASTNode
+	listItems      []basicwidget.ListItem[int]
+	onNodeSelected func(n ast.Node)
+}
+
+// SetOnNodeSelected registers a callback for clicks on rows that refer to a
+// syntax node or a token
+func (s *SidePane) SetOnNodeSelected(f func(n ast.Node)) {
+	s.onNodeSelected = f
+}
+
+// SetNodes replaces the rows shown
+func (s *SidePane) SetNodes(title string, nodes []*ASTNode) {
+	s.title = title
+	s.nodes = nodes
+}
+
+func (s *SidePane) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	adder.AddChild(&s.titleText)
+	adder.AddChild(&s.list)
+
+	s.titleText.SetValue(s.title)
+	s.titleText.SetBold(true)
+
+	s.listItems = treeListItems(s.listItems[:0], s.nodes)
+	s.list.SetItems(s.listItems)
+	s.list.SetStripeVisible(true)
+	s.list.SetOnItemSelected(func(index int) {
+		flatNodes := FlattenNodes(s.nodes)
+		if index < 0 || index >= len(flatNodes) {
+			return
+		}
+		node := flatNodes[index]
+		if node.Node != nil && s.onNodeSelected != nil {
+			s.onNodeSelected(node.Node)
+			return
+		}
+		if len(node.Children) > 0 {
+			node.Collapsed = !node.Collapsed
+		}
+	})
+	s.list.SetOnItemExpanderToggled(func(index int, expanded bool) {
+		flatNodes := FlattenNodes(s.nodes)
+		if index < len(flatNodes) {
+			flatNodes[index].Collapsed = !expanded
+		}
+	})
+
+	return nil
+}
+
+func (s *SidePane) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionVertical,
+		Items: []guigui.LinearLayoutItem{
+			{
+				Widget: &s.titleText,
+			},
+			{
+				Widget: &s.list,
+				Size:   guigui.FlexibleSize(1),
+			},
+		},
+		Gap: u / 4,
+		Padding: guigui.Padding{
+			Start:  u / 2,
+			Top:    u / 2,
+			End:    u / 2,
+			Bottom: u / 2,
+		},
+	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+}
+
+func (s *SidePane) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, 20*u)
+	}
+	return image.Pt(20*u, 20*u)
+}
+
+// sidePaneKey identifies what the rows of the third pane were derived from
+type sidePaneKey struct {
+	kind     sidePaneKind
+	archive  *Archive
+	typeInfo *TypeInfo
+	selected *ASTNode
+
+	// Errors are not always comparable, so a failed parse is told apart by
+	// the source it failed on
+	parseFailed bool
+	source      string
+}
+
+// SidePaneNodes returns the title and rows of the third pane. The rows are
+// only recomputed when what they are derived from changes, so that their
+// expansion state is kept.
+func (r *RightPanel) SidePaneNodes(kind sidePaneKind) (string, []*ASTNode) {
+	key := sidePaneKey{
+		kind:     kind,
+		archive:  r.archive,
+		typeInfo: r.typeInfo,
+	}
+	if kind == sidePaneInspector {
+		key.selected = r.selected
+	}
+	if r.parseErr != nil {
+		key.parseFailed = true
+		key.source = r.source
+	}
+	if key == r.sidePaneKey && r.sidePaneNodes != nil {
+		return r.sidePaneTitle, r.sidePaneNodes
+	}
+	r.sidePaneKey = key
+
+	switch kind {
+	case sidePaneTokens:
+		r.sidePaneTitle = "Tokens"
+		r.sidePaneNodes = nil
+		if r.archive != nil {
+			r.sidePaneNodes = TokenNodes(r.archive)
+		}
+	case sidePaneDiagnostics:
+		r.sidePaneTitle = "Diagnostics"
+		r.sidePaneNodes = ProblemNodes(r.archive, r.typeInfo, r.parseErr)
+	default:
+		if r.hasInspection() {
+			r.sidePaneTitle = "Inspector: " + nodeSummary(r.selected.Node)
+			r.sidePaneNodes = InspectNodes(r.archive, r.typeInfo, r.selected.Node)
+			break
+		}
+		r.sidePaneTitle = "Inspector"
+		r.sidePaneNodes = []*ASTNode{{
+			Label:       "Select a node in the AST tree",
+			IndentLevel: 1,
+		}}
+	}
+	return r.sidePaneTitle, r.sidePaneNodes
+}
+
+// ProblemNodes lists the problems found in the archive: the error that
+// stopped it from being parsed, the syntax errors of its files and the type
+// errors
+func ProblemNodes(a *Archive, ti *TypeInfo, parseErr error) []*ASTNode {
+	if parseErr != nil {
+		return []*ASTNode{{
+			Label:       "Error: " + parseErr.Error(),
+			IndentLevel: 1,
+			Color:       testFailColor,
+		}}
+	}
+	if a == nil {
+		return nil
+	}
+
+	syntaxNode := &ASTNode{
+		IndentLevel: 1,
+	}
+	for _, err := range a.SyntaxErrors {
+		var list scanner.ErrorList
+		if !errors.As(err, &list) {
+			syntaxNode.Children = append(syntaxNode.Children, &ASTNode{
+				Label:       err.Error(),
+				IndentLevel: 2,
+				Color:       testFailColor,
+			})
+			continue
+		}
+		for _, e := range list {
+			syntaxNode.Children = append(syntaxNode.Children, &ASTNode{
+				Label:       e.Error(),
+				IndentLevel: 2,
+				Color:       testFailColor,
+			})
+		}
+	}
+	syntaxNode.Label = fmt.Sprintf("Syntax errors (%d)", len(syntaxNode.Children))
+
+	var typeErrors []types.Error
+	if ti != nil {
+		typeErrors = slices.Clone(ti.Errors)
+	}
+	nodes := []*ASTNode{
+		syntaxNode,
+		typeErrorsToNode(a, "Type errors", typeErrors, testFailColor),
+	}
+	LinkParents(nodes, nil)
+	return nodes
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/guigui-gui/guigui"
+)
+
+var (
+	splitterColor         = color.RGBA{R: 0xd0, G: 0xd7, B: 0xde, A: 0xff}
+	splitterDraggingColor = color.RGBA{R: 0x54, G: 0xae, B: 0xff, A: 0xff}
+)
+
+// Splitter is the handle between two panes, dragged to move the boundary
+// between them
+type Splitter struct {
+	guigui.DefaultWidget
+
+	// vertical is set when the panes are stacked, so that the handle is
+	// dragged up and down
+	vertical bool
+	dragging bool
+
+	onDragged   func(pos image.Point)
+	onDragEnded func()
+}
+
+// SetVertical sets whether the panes on either side are stacked
+func (s *Splitter) SetVertical(vertical bool) {
+	s.vertical = vertical
+}
+
+// SetOnDragged registers a callback receiving the cursor position while the
+// handle is dragged
+func (s *Splitter) SetOnDragged(f func(pos image.Point)) {
+	s.onDragged = f
+}
+
+// SetOnDragEnded registers a callback for the release of the handle
+func (s *Splitter) SetOnDragEnded(f func()) {
+	s.onDragEnded = f
+}
+
+func (s *Splitter) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	return nil
+}
+
+func (s *Splitter) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+}
+
+func (s *Splitter) HandlePointingInput(context *guigui.Context, widgetBounds *guigui.WidgetBounds) guigui.HandleInputResult {
+	if s.dragging {
+		if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			s.dragging = false
+			if s.onDragEnded != nil {
+				s.onDragEnded()
+			}
+			return guigui.HandleInputResult{}
+		}
+		if s.onDragged != nil {
+			s.onDragged(image.Pt(ebiten.CursorPosition()))
+		}
+		return guigui.HandleInputByWidget(s)
+	}
+	if widgetBounds.IsHitAtCursor() && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		s.dragging = true
+		return guigui.HandleInputByWidget(s)
+	}
+	return guigui.HandleInputResult{}
+}
+
+func (s *Splitter) CursorShape(context *guigui.Context, widgetBounds *guigui.WidgetBounds) (ebiten.CursorShapeType, bool) {
+	if !s.dragging && !widgetBounds.IsHitAtCursor() {
+		return 0, false
+	}
+	if s.vertical {
+		return ebiten.CursorShapeNSResize, true
+	}
+	return ebiten.CursorShapeEWResize, true
+}
+
+// Draw draws a line along the middle of the handle, or fills it while it is
+// dragged
+func (s *Splitter) Draw(context *guigui.Context, widgetBounds *guigui.WidgetBounds, dst *ebiten.Image) {
+	bounds := widgetBounds.Bounds()
+	if s.dragging {
+		dst.SubImage(bounds).(*ebiten.Image).Fill(splitterDraggingColor)
+		return
+	}
+	line := bounds
+	if s.vertical {
+		line.Min.Y = bounds.Min.Y + bounds.Dy()/2
+		line.Max.Y = line.Min.Y + 1
+	} else {
+		line.Min.X = bounds.Min.X + bounds.Dx()/2
+		line.Max.X = line.Min.X + 1
+	}
+	dst.SubImage(line).(*ebiten.Image).Fill(splitterColor)
+}
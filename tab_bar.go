@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"image"
+
+	"github.com/guigui-gui/guigui"
+	"github.com/guigui-gui/guigui/basicwidget"
+)
+
+// TabBar switches between workspaces, with a button per tab and buttons to
+// open a new tab and close the current one. It also holds the controls
+// arranging the panes.
+type TabBar struct {
+	guigui.DefaultWidget
+
+	tabButtons       []basicwidget.Button
+	newButton        basicwidget.Button
+	stackButton      basicwidget.Button
+	sidePaneDropdown basicwidget.DropdownList[sidePaneKind]
+	closeButton      basicwidget.Button
+
+	names           []string
+	current         int
+	vertical        bool
+	sidePane        sidePaneKind
+	onSelected      func(index int)
+	onNew           func()
+	onClose         func()
+	onLayoutChanged func(vertical bool, sidePane sidePaneKind)
+}
+
+// SetTabs replaces the tab names and the index of the current tab
+func (b *TabBar) SetTabs(names []string, current int) {
+	b.names = names
+	b.current = current
+}
+
+// SetLayout sets the pane arrangement the layout controls show
+func (b *TabBar) SetLayout(vertical bool, sidePane sidePaneKind) {
+	b.vertical = vertical
+	b.sidePane = sidePane
+}
+
+func (b *TabBar) SetOnLayoutChanged(f func(vertical bool, sidePane sidePaneKind)) {
+	b.onLayoutChanged = f
+}
+
+func (b *TabBar) layoutChanged() {
+	if b.onLayoutChanged != nil {
+		b.onLayoutChanged(b.vertical, b.sidePane)
+	}
+}
+
+func (b *TabBar) SetOnSelected(f func(index int)) {
+	b.onSelected = f
+}
+
+func (b *TabBar) SetOnNew(f func()) {
+	b.onNew = f
+}
+
+func (b *TabBar) SetOnClose(f func()) {
+	b.onClose = f
+}
+
+func (b *TabBar) Build(context *guigui.Context, adder *guigui.ChildAdder) error {
+	if len(b.tabButtons) != len(b.names) {
+		b.tabButtons = make([]basicwidget.Button, len(b.names))
+	}
+
+	for i, name := range b.names {
+		adder.AddChild(&b.tabButtons[i])
+		if i == b.current {
+			name = "● " + name
+		}
+		b.tabButtons[i].SetText(name)
+		b.tabButtons[i].SetOnDown(func() {
+			if b.onSelected != nil {
+				b.onSelected(i)
+			}
+		})
+	}
+
+	adder.AddChild(&b.newButton)
+	b.newButton.SetText("+")
+	b.newButton.SetOnDown(func() {
+		if b.onNew != nil {
+			b.onNew()
+		}
+	})
+
+	adder.AddChild(&b.stackButton)
+	if b.vertical {
+		b.stackButton.SetText("Side by Side")
+	} else {
+		b.stackButton.SetText("Stack Vertically")
+	}
+	b.stackButton.SetOnDown(func() {
+		b.vertical = !b.vertical
+		b.layoutChanged()
+	})
+
+	adder.AddChild(&b.sidePaneDropdown)
+	b.sidePaneDropdown.SetItems(sidePaneItems)
+	b.sidePaneDropdown.SelectItemByValue(b.sidePane)
+	b.sidePaneDropdown.SetOnItemSelected(func(index int) {
+		b.sidePane = sidePaneItems[index].Value
+		b.layoutChanged()
+	})
+
+	if len(b.names) > 1 {
+		adder.AddChild(&b.closeButton)
+		b.closeButton.SetText("Close Tab")
+		b.closeButton.SetOnDown(func() {
+			if b.onClose != nil {
+				b.onClose()
+			}
+		})
+	}
+
+	return nil
+}
+
+func (b *TabBar) Layout(context *guigui.Context, widgetBounds *guigui.WidgetBounds, layouter *guigui.ChildLayouter) {
+	u := basicwidget.UnitSize(context)
+
+	var items []guigui.LinearLayoutItem
+	for i := range b.tabButtons {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &b.tabButtons[i],
+		})
+	}
+	items = append(items, guigui.LinearLayoutItem{
+		Widget: &b.newButton,
+	}, guigui.LinearLayoutItem{
+		Size: guigui.FlexibleSize(1),
+	}, guigui.LinearLayoutItem{
+		Widget: &b.stackButton,
+	}, guigui.LinearLayoutItem{
+		Widget: &b.sidePaneDropdown,
+	})
+	if len(b.names) > 1 {
+		items = append(items, guigui.LinearLayoutItem{
+			Widget: &b.closeButton,
+		})
+	}
+
+	(guigui.LinearLayout{
+		Direction: guigui.LayoutDirectionHorizontal,
+		Items:     items,
+		Gap:       u / 4,
+		Padding: guigui.Padding{
+			Start:  u / 2,
+			Top:    u / 4,
+			End:    u / 2,
+			Bottom: u / 4,
+		},
+	}).LayoutWidgets(context, widgetBounds.Bounds(), layouter)
+}
+
+func (b *TabBar) Measure(context *guigui.Context, constraints guigui.Constraints) image.Point {
+	u := basicwidget.UnitSize(context)
+	h := b.newButton.Measure(context, guigui.Constraints{}).Y + u/2
+	if w, ok := constraints.FixedWidth(); ok {
+		return image.Pt(w, h)
+	}
+	return image.Pt(20*u, h)
+}
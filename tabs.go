@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Tab is an independent workspace with its own editor buffer, parse
+// result, view mode and tree expansion state
+type Tab struct {
+	Name string
+
+	leftPanel  LeftPanel
+	rightPanel RightPanel
+}
+
+// SetSource replaces the buffer of the tab and parses it
+func (t *Tab) SetSource(source string) {
+	t.leftPanel.SetSource(source)
+	t.rightPanel.SetSource(source)
+}
+
+// currentTab returns the tab shown in the window
+func (r *Root) currentTab() *Tab {
+	return r.tabs[r.current]
+}
+
+// tabNames returns the names of the tabs for the tab bar
+func (r *Root) tabNames() []string {
+	names := make([]string, len(r.tabs))
+	for i, t := range r.tabs {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// newTabName returns a name not used by any tab yet
+func (r *Root) newTabName() string {
+	for i := len(r.tabs) + 1; ; i++ {
+		name := fmt.Sprintf("Workspace %d", i)
+		if !slices.ContainsFunc(r.tabs, func(t *Tab) bool { return t.Name == name }) {
+			return name
+		}
+	}
+}
+
+// addTab opens a new tab holding the sample source and switches to it
+func (r *Root) addTab() {
+	t := &Tab{Name: r.newTabName()}
+	t.SetSource(defaultSource)
+	r.tabs = append(r.tabs, t)
+	r.selectTab(len(r.tabs) - 1)
+}
+
+// selectTab switches to the tab at index
+func (r *Root) selectTab(index int) {
+	if index < 0 || index >= len(r.tabs) {
+		return
+	}
+	r.current = index
+	r.saveSession()
+}
+
+// stopRunners stops the commands running in every tab and waits for them to
+// exit
+func (r *Root) stopRunners() {
+	for _, t := range r.tabs {
+		t.rightPanel.runner.Stop()
+	}
+	for _, t := range r.tabs {
+		t.rightPanel.runner.Wait()
+	}
+}
+
+// closeTab closes the current tab, keeping at least one open. A command
+// still running in it is stopped, as nothing could stop it later.
+func (r *Root) closeTab() {
+	if len(r.tabs) <= 1 {
+		return
+	}
+	r.tabs[r.current].rightPanel.runner.Stop()
+	r.tabs = slices.Delete(r.tabs, r.current, r.current+1)
+	r.selectTab(min(r.current, len(r.tabs)-1))
+}
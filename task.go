@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import "sync"
+
+// Task runs slow work such as a build of the archive in the background, so
+// that the UI stays responsive, and hands the result back to be applied on
+// the UI goroutine. It is safe for concurrent use.
+type Task struct {
+	mu      sync.Mutex
+	running bool
+	apply   func()
+}
+
+// Start runs work in the background unless a previous run is still going.
+// work returns a function applying its result, which Poll calls.
+func (t *Task) Start(work func() func()) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return false
+	}
+	t.running = true
+	t.apply = nil
+
+	go func() {
+		apply := work()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.running = false
+		t.apply = apply
+	}()
+	return true
+}
+
+// Running reports whether the work is running
+func (t *Task) Running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+// Poll applies the result of a finished run, if there is one, and reports
+// whether it did
+func (t *Task) Poll() bool {
+	t.mu.Lock()
+	apply := t.apply
+	t.apply = nil
+	t.mu.Unlock()
+
+	if apply == nil {
+		return false
+	}
+	apply()
+	return true
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTask(t *testing.T) {
+	var task Task
+	release := make(chan struct{})
+	var got int
+	if !task.Start(func() func() {
+		<-release
+		return func() { got = 42 }
+	}) {
+		t.Fatal("Start failed on an idle task")
+	}
+	if task.Start(func() func() { return nil }) {
+		t.Error("Start succeeded while running")
+	}
+	if task.Poll() {
+		t.Error("Poll applied a result before the work finished")
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for !task.Poll() {
+		if time.Now().After(deadline) {
+			t.Fatal("the result was never applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got != 42 {
+		t.Errorf("got %d, want 42", got)
+	}
+	if task.Running() || task.Poll() {
+		t.Error("the task is not idle after its result was applied")
+	}
+}
@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"go/ast"
+	"image/color"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	testPassColor = color.RGBA{R: 0x1a, G: 0x7f, B: 0x37, A: 0xff}
+	testFailColor = color.RGBA{R: 0xcf, G: 0x22, B: 0x2e, A: 0xff}
+)
+
+// testKind is the kind of function go test runs
+type testKind int
+
+const (
+	testKindTest testKind = iota
+	testKindBenchmark
+	testKindFuzz
+	testKindExample
+)
+
+var testPrefixes = map[testKind]string{
+	testKindTest:      "Test",
+	testKindBenchmark: "Benchmark",
+	testKindFuzz:      "Fuzz",
+	testKindExample:   "Example",
+}
+
+// TestFunc is a test, benchmark, fuzz target or example declared in a
+// _test.go file of the archive
+type TestFunc struct {
+	Name    string
+	Kind    testKind
+	Package string
+	Decl    *ast.FuncDecl
+
+	// NoOutput is set for examples without an output comment, which go test
+	// compiles but does not run
+	NoOutput bool
+}
+
+// key identifies a test across parses of the archive
+func (t TestFunc) key() string {
+	return t.Package + "." + t.Name
+}
+
+// Args returns the arguments of a go test command running only t. Fuzz
+// targets run their seed corpus.
+func (t TestFunc) Args() []string {
+	pattern := "^" + regexp.QuoteMeta(t.Name) + "$"
+	if t.Kind == testKindBenchmark {
+		return []string{"test", "-v", "-run", "^$", "-bench", pattern, t.Package}
+	}
+	return []string{"test", "-v", "-run", pattern, t.Package}
+}
+
+// TestFuncs returns the functions of the _test.go files of the archive that
+// go test would run, in source order
+func (a *Archive) TestFuncs() []TestFunc {
+	var tests []TestFunc
+	for _, f := range a.Files {
+		name := a.Fset.File(f.Pos()).Name()
+		if !strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			if kind, ok := testKindOf(fd); ok {
+				tests = append(tests, TestFunc{
+					Name:     fd.Name.Name,
+					Kind:     kind,
+					Package:  goPackagePath(name),
+					Decl:     fd,
+					NoOutput: kind == testKindExample && !hasOutputComment(f, fd),
+				})
+			}
+		}
+	}
+	return tests
+}
+
+// testKindOf reports whether a function is run by go test, following the
+// naming rules of the testing package
+func testKindOf(fd *ast.FuncDecl) (testKind, bool) {
+	if fd.Recv != nil || fd.Type.TypeParams != nil {
+		return 0, false
+	}
+	for _, kind := range []testKind{testKindTest, testKindBenchmark, testKindFuzz, testKindExample} {
+		rest, ok := strings.CutPrefix(fd.Name.Name, testPrefixes[kind])
+		if !ok {
+			continue
+		}
+		// TestMain sets up the tests, and Testing is not a test because of the
+		// lowercase letter after the prefix
+		if kind == testKindTest && rest == "Main" {
+			return 0, false
+		}
+		if r, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(r) {
+			return 0, false
+		}
+		params := fd.Type.Params.NumFields()
+		if kind == testKindExample {
+			return kind, params == 0
+		}
+		return kind, params == 1
+	}
+	return 0, false
+}
+
+// outputCommentRE matches the comment that holds the expected output of an
+// example
+var outputCommentRE = regexp.MustCompile(`(?i)^[[:space:]]*(unordered )?output:`)
+
+// hasOutputComment reports whether the last comment in the body of an
+// example gives its expected output, the rule go test uses to decide whether
+// to run it
+func hasOutputComment(f *ast.File, fd *ast.FuncDecl) bool {
+	if fd.Body == nil {
+		return false
+	}
+	var last *ast.CommentGroup
+	for _, cg := range f.Comments {
+		if cg.Pos() > fd.Body.Lbrace && cg.End() < fd.Body.Rbrace {
+			last = cg
+		}
+	}
+	return last != nil && outputCommentRE.MatchString(last.Text())
+}
+
+// benchmarkSuffixRE matches the -N suffix go test adds to the name of a
+// benchmark when GOMAXPROCS is not 1
+var benchmarkSuffixRE = regexp.MustCompile(`-[0-9]+$`)
+
+// TestResult is the outcome of running a single test
+type TestResult struct {
+	Running bool
+	Passed  bool
+	NotRun  bool
+	Summary string
+	Output  []string
+}
+
+// parseTestOutput finds the outcome of a test in the output of go test -v
+func parseTestOutput(t TestFunc, lines []ConsoleLine) TestResult {
+	result := TestResult{
+		Summary: "FAIL",
+	}
+	// The first line echoes the command
+	if len(lines) > 0 {
+		lines = lines[1:]
+	}
+	for _, line := range lines {
+		result.Output = append(result.Output, line.Text)
+		text := strings.TrimSpace(line.Text)
+		fields := strings.Fields(text)
+		switch {
+		case t.Kind == testKindBenchmark && len(fields) > 0 && benchmarkSuffixRE.ReplaceAllString(fields[0], "") == t.Name && strings.Contains(text, "ns/op"):
+			result.Summary = "PASS: " + strings.Join(fields[1:], " ")
+		case strings.HasPrefix(text, "--- ") && strings.Contains(text, ": "+t.Name+" "):
+			result.Summary = strings.TrimPrefix(text, "--- ")
+		case strings.HasSuffix(text, "[build failed]") || strings.HasSuffix(text, "[setup failed]"):
+			result.Summary = "FAIL: the package does not build"
+		case text == "ok" || strings.HasPrefix(text, "ok "):
+			result.Passed = true
+		}
+	}
+	if result.Passed && result.Summary == "FAIL" {
+		result.Summary = "PASS"
+		if t.NoOutput {
+			result.Passed = false
+			result.NotRun = true
+			result.Summary = "not run: the example has no output comment"
+		}
+	}
+	return result
+}
+
+// AnnotateTests adds a row to every test function in the tree that runs it
+// when clicked, showing the outcome of its last run. It returns the test of
+// each such row.
+func AnnotateTests(a *Archive, tests []TestFunc, results map[string]TestResult) map[*ASTNode]TestFunc {
+	rows := make(map[*ASTNode]TestFunc)
+	for _, t := range tests {
+		path := FindPath(a.Nodes, t.Decl)
+		if path == nil {
+			continue
+		}
+		node := path[len(path)-1]
+		row := &ASTNode{
+			Label:       "▶ Run " + t.Name,
+			IndentLevel: node.IndentLevel + 1,
+			Node:        t.Decl,
+			Parent:      node,
+			Annotation:  true,
+			Collapsed:   true,
+		}
+		if result, ok := results[t.key()]; ok {
+			testResultToNode(row, result)
+		}
+		LinkParents(row.Children, row)
+		node.Children = append([]*ASTNode{row}, node.Children...)
+		rows[row] = t
+	}
+	return rows
+}
+
+// testResultToNode shows the outcome and output of a test run on its row
+func testResultToNode(row *ASTNode, result TestResult) {
+	if result.Running {
+		row.Label += ": running…"
+		return
+	}
+	row.Label += ": " + result.Summary
+	switch {
+	case result.Passed:
+		row.Color = testPassColor
+	case !result.NotRun:
+		row.Color = testFailColor
+	}
+	for _, line := range result.Output {
+		row.Children = append(row.Children, &ASTNode{
+			Label:       line,
+			IndentLevel: row.IndentLevel + 1,
+			Node:        row.Node,
+		})
+	}
+}
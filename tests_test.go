@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import "testing"
+
+// consoleLines turns output into console lines after the echoed command
+func consoleLines(output ...string) []ConsoleLine {
+	lines := []ConsoleLine{{Text: "$ go test"}}
+	for _, text := range output {
+		lines = append(lines, ConsoleLine{Text: text})
+	}
+	return lines
+}
+
+func TestParseTestOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		test    TestFunc
+		lines   []ConsoleLine
+		passed  bool
+		notRun  bool
+		summary string
+	}{
+		{
+			name:    "test passes",
+			test:    TestFunc{Name: "TestX", Kind: testKindTest},
+			lines:   consoleLines("=== RUN   TestX", "--- PASS: TestX (0.00s)", "PASS", "ok  \tm\t0.01s"),
+			passed:  true,
+			summary: "PASS: TestX (0.00s)",
+		},
+		{
+			name:    "test fails",
+			test:    TestFunc{Name: "TestX", Kind: testKindTest},
+			lines:   consoleLines("=== RUN   TestX", "--- FAIL: TestX (0.00s)", "FAIL", "FAIL\tm\t0.01s"),
+			summary: "FAIL: TestX (0.00s)",
+		},
+		{
+			name:    "build fails",
+			test:    TestFunc{Name: "TestX", Kind: testKindTest},
+			lines:   consoleLines("./a_test.go:3:1: syntax error", "FAIL\tm [build failed]"),
+			summary: "FAIL: the package does not build",
+		},
+		{
+			name: "benchmark ignores longer names",
+			test: TestFunc{Name: "BenchmarkY", Kind: testKindBenchmark},
+			lines: consoleLines(
+				"BenchmarkY-8   \t1000\t 123 ns/op",
+				"BenchmarkYZ-8  \t2000\t 456 ns/op",
+				"PASS", "ok  \tm\t1.01s",
+			),
+			passed:  true,
+			summary: "PASS: 1000 123 ns/op",
+		},
+		{
+			name:    "benchmark without suffix",
+			test:    TestFunc{Name: "BenchmarkY", Kind: testKindBenchmark},
+			lines:   consoleLines("BenchmarkY \t1000\t 123 ns/op", "PASS", "ok  \tm\t1.01s"),
+			passed:  true,
+			summary: "PASS: 1000 123 ns/op",
+		},
+		{
+			name:    "example without output",
+			test:    TestFunc{Name: "ExampleX", Kind: testKindExample, NoOutput: true},
+			lines:   consoleLines("testing: warning: no tests to run", "PASS", "ok  \tm\t0.01s"),
+			notRun:  true,
+			summary: "not run: the example has no output comment",
+		},
+		{
+			name:    "no output",
+			test:    TestFunc{Name: "TestX", Kind: testKindTest},
+			summary: "FAIL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTestOutput(tt.test, tt.lines)
+			if got.Passed != tt.passed || got.NotRun != tt.notRun || got.Summary != tt.summary {
+				t.Errorf("got passed %v, not run %v, %q; want %v, %v, %q", got.Passed, got.NotRun, got.Summary, tt.passed, tt.notRun, tt.summary)
+			}
+		})
+	}
+}
+
+func TestTestFuncsNoOutput(t *testing.T) {
+	a, err := ParseTxtar(`-- a_test.go --
+package a
+
+import "fmt"
+
+func ExampleWithOutput() {
+	fmt.Println("hi")
+	// Output: hi
+}
+
+func ExampleUnordered() {
+	fmt.Println("hi")
+	// unordered output:
+	// hi
+}
+
+func ExampleWithout() {
+	// Output: is not the last comment
+	fmt.Println("hi")
+	// done
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"ExampleWithOutput": false,
+		"ExampleUnordered":  false,
+		"ExampleWithout":    true,
+	}
+	tests := a.TestFuncs()
+	if len(tests) != len(want) {
+		t.Fatalf("got %d examples, want %d", len(tests), len(want))
+	}
+	for _, test := range tests {
+		if test.NoOutput != want[test.Name] {
+			t.Errorf("%s: NoOutput = %v, want %v", test.Name, test.NoOutput, want[test.Name])
+		}
+	}
+}
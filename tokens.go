@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// tokenSpan is the source range of a token, standing in for a syntax node
+// so that clicking a token row selects it in the editor
+type tokenSpan struct {
+	pos, end token.Pos
+}
+
+func (s tokenSpan) Pos() token.Pos { return s.pos }
+func (s tokenSpan) End() token.Pos { return s.end }
+
+// TokenNodes lists the tokens the scanner produces for each Go file of the
+// archive, including comments and the semicolons it inserts
+func TokenNodes(a *Archive) []*ASTNode {
+	var nodes []*ASTNode
+	for _, f := range a.Files {
+		tf := a.Fset.File(f.Pos())
+		src := archiveFileData(a.Txtar, tf.Name())
+		if len(src) != tf.Size() {
+			continue
+		}
+
+		fileNode := &ASTNode{
+			IndentLevel: 1,
+			Node:        f,
+		}
+		var s scanner.Scanner
+		s.Init(tf, src, nil, scanner.ScanComments)
+		for {
+			pos, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			fileNode.Children = append(fileNode.Children, tokenToNode(tf, pos, tok, lit))
+		}
+		fileNode.Label = fmt.Sprintf("File: %s (%d tokens)", tf.Name(), len(fileNode.Children))
+		nodes = append(nodes, fileNode)
+	}
+	LinkParents(nodes, nil)
+	return nodes
+}
+
+// tokenToNode converts a token to a display node
+func tokenToNode(tf *token.File, pos token.Pos, tok token.Token, lit string) *ASTNode {
+	p := tf.Position(pos)
+	label := fmt.Sprintf("%d:%d %s", p.Line, p.Column, tok)
+	size := len(lit)
+	switch {
+	case tok == token.SEMICOLON && lit != ";":
+		label += " (inserted by the scanner)"
+		size = 0
+	case lit != "" && lit != tok.String():
+		// Comments and raw strings may span lines
+		text, _, multiline := strings.Cut(lit, "\n")
+		if multiline {
+			text += "…"
+		}
+		label += " " + elide(text, maxExprLen)
+	default:
+		size = len(tok.String())
+	}
+	return &ASTNode{
+		Label:       label,
+		IndentLevel: 2,
+		Node:        tokenSpan{pos: pos, end: pos + token.Pos(size)},
+	}
+}
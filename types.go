@@ -0,0 +1,285 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"image/color"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// TypeInfo is the result of type-checking the packages of an archive
+type TypeInfo struct {
+	// GoVersion is the language version used, or "" for the toolchain's
+	GoVersion string
+	Packages  []*types.Package
+	Info      *types.Info
+	Errors    []types.Error
+
+	// importer is kept so that further packages can be loaded with types
+	// identical to those seen by the checked files
+	importer types.Importer
+}
+
+// TypeCheck type-checks the Go files of the archive under the given
+// language version. Files are grouped into packages by directory and
+// package name. Type errors are collected rather than returned. Packages
+// using cgo are checked with a fake C package that accepts any C.xxx.
+func (a *Archive) TypeCheck(goVersion string) *TypeInfo {
+	ti := &TypeInfo{
+		GoVersion: goVersion,
+		Info: &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Instances:  make(map[*ast.Ident]types.Instance),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		},
+	}
+
+	imp := importer.ForCompiler(a.Fset, "gc", nil)
+	ti.importer = imp
+	for _, files := range a.packageFiles() {
+		conf := types.Config{
+			GoVersion:   goVersion,
+			Importer:    imp,
+			FakeImportC: usesCgo(files),
+			Error: func(err error) {
+				var terr types.Error
+				if errors.As(err, &terr) {
+					ti.Errors = append(ti.Errors, terr)
+				}
+			},
+		}
+		pkg, _ := conf.Check(files[0].Name.Name, a.Fset, files, ti.Info)
+		ti.Packages = append(ti.Packages, pkg)
+	}
+
+	return ti
+}
+
+// packageFiles groups the files of the archive by directory and package name
+func (a *Archive) packageFiles() [][]*ast.File {
+	var keys []string
+	groups := make(map[string][]*ast.File)
+	for _, f := range a.Files {
+		key := path.Dir(a.Fset.File(f.Pos()).Name()) + "\x00" + f.Name.Name
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], f)
+	}
+
+	var pkgs [][]*ast.File
+	for _, key := range keys {
+		pkgs = append(pkgs, groups[key])
+	}
+	return pkgs
+}
+
+// GoVersion returns the language version declared by the go directive of
+// the archive's go.mod, or "" if there is none
+func (a *Archive) GoVersion() string {
+	data := archiveFileData(a.Txtar, "go.mod")
+	if data == nil {
+		return ""
+	}
+	mf, err := modfile.Parse("go.mod", data, nil)
+	if err != nil || mf.Go == nil {
+		return ""
+	}
+	return "go" + mf.Go.Version
+}
+
+// goVersions lists the language versions that can be selected, from the
+// first version with generics up to the running toolchain
+func goVersions() []string {
+	latest := 18
+	if v, ok := strings.CutPrefix(runtime.Version(), "go1."); ok {
+		minor, _, _ := strings.Cut(v, ".")
+		if n, err := strconv.Atoi(minor); err == nil {
+			latest = n
+		}
+	}
+
+	var versions []string
+	for minor := 18; minor <= latest; minor++ {
+		versions = append(versions, fmt.Sprintf("go1.%d", minor))
+	}
+	return versions
+}
+
+// enclosingNodes returns the syntax nodes enclosing pos, innermost first
+func (a *Archive) enclosingNodes(pos token.Pos) []ast.Node {
+	for _, f := range a.Files {
+		if f.FileStart <= pos && pos <= f.FileEnd {
+			path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+			return path
+		}
+	}
+	return nil
+}
+
+// displayedNodeAt returns the innermost syntax node enclosing pos that is
+// shown in the AST tree
+func (a *Archive) displayedNodeAt(pos token.Pos) ast.Node {
+	for _, n := range a.enclosingNodes(pos) {
+		if FindPath(a.Nodes, n) != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// AnnotateInstances adds the type arguments inferred or given at every
+// generic call site and instantiation in the tree
+func AnnotateInstances(nodes []*ASTNode, ti *TypeInfo) {
+	for _, node := range nodes {
+		AnnotateInstances(node.Children, ti)
+
+		var ident *ast.Ident
+		switch n := node.Node.(type) {
+		case *ast.CallExpr:
+			// Explicit instantiations are annotated on the Fun node
+			switch ast.Unparen(n.Fun).(type) {
+			case *ast.IndexExpr, *ast.IndexListExpr:
+			default:
+				ident = instanceIdent(n.Fun)
+			}
+		case *ast.IndexExpr, *ast.IndexListExpr:
+			ident = instanceIdent(n.(ast.Expr))
+		}
+		if ident == nil {
+			continue
+		}
+		inst, ok := ti.Info.Instances[ident]
+		if !ok {
+			continue
+		}
+
+		args := make([]string, inst.TypeArgs.Len())
+		for i := range args {
+			args[i] = types.TypeString(inst.TypeArgs.At(i), packageName)
+		}
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("Instance: %s[%s] %s", ident.Name, strings.Join(args, ", "), types.TypeString(inst.Type, packageName)),
+			IndentLevel: node.IndentLevel + 1,
+			Node:        ident,
+			Parent:      node,
+			Annotation:  true,
+		})
+	}
+}
+
+// instanceIdent returns the identifier that names a possibly instantiated
+// function or type
+func instanceIdent(expr ast.Expr) *ast.Ident {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return instanceIdent(e.X)
+	case *ast.IndexListExpr:
+		return instanceIdent(e.X)
+	}
+	return nil
+}
+
+// packageName qualifies package members by package name in type strings
+func packageName(pkg *types.Package) string {
+	return pkg.Name()
+}
+
+// VersionCompareNodes compares the type check result tiA with one under
+// another language version and lists the errors reported by only one of
+// them or by both
+func VersionCompareNodes(a *Archive, tiA *TypeInfo, versionB string) []*ASTNode {
+	versionA := tiA.GoVersion
+	tiB := tiA
+	if versionB != versionA {
+		tiB = a.TypeCheck(versionB)
+	}
+
+	key := func(err types.Error) string {
+		return fmt.Sprintf("%d\x00%s", err.Pos, err.Msg)
+	}
+	inA := make(map[string]bool)
+	for _, err := range tiA.Errors {
+		inA[key(err)] = true
+	}
+	inB := make(map[string]bool)
+	for _, err := range tiB.Errors {
+		inB[key(err)] = true
+	}
+
+	var onlyA, onlyB, both []types.Error
+	for _, err := range tiA.Errors {
+		if inB[key(err)] {
+			both = append(both, err)
+		} else {
+			onlyA = append(onlyA, err)
+		}
+	}
+	for _, err := range tiB.Errors {
+		if !inA[key(err)] {
+			onlyB = append(onlyB, err)
+		}
+	}
+
+	return []*ASTNode{
+		{
+			Label:       fmt.Sprintf("%s: %d errors, %s: %d errors", versionLabel(versionA), len(tiA.Errors), versionLabel(versionB), len(tiB.Errors)),
+			IndentLevel: 1,
+		},
+		typeErrorsToNode(a, fmt.Sprintf("Only with %s", versionLabel(versionA)), onlyA, diffDeletedColor),
+		typeErrorsToNode(a, fmt.Sprintf("Only with %s", versionLabel(versionB)), onlyB, diffInsertedColor),
+		typeErrorsToNode(a, "With both", both, nil),
+	}
+}
+
+// versionLabel names a language version for display
+func versionLabel(version string) string {
+	if version == "" {
+		return runtime.Version()
+	}
+	return version
+}
+
+// typeErrorsToNode lists type errors linked to the nodes they occur at
+func typeErrorsToNode(a *Archive, title string, errs []types.Error, c color.Color) *ASTNode {
+	slices.SortFunc(errs, func(x, y types.Error) int {
+		return cmp.Compare(x.Pos, y.Pos)
+	})
+
+	node := &ASTNode{
+		Label:       fmt.Sprintf("%s (%d)", title, len(errs)),
+		IndentLevel: 1,
+	}
+	for _, err := range errs {
+		pos := err.Fset.Position(err.Pos)
+		node.Children = append(node.Children, &ASTNode{
+			Label:       fmt.Sprintf("%s:%d:%d: %s", pos.Filename, pos.Line, pos.Column, err.Msg),
+			IndentLevel: 2,
+			Node:        a.displayedNodeAt(err.Pos),
+			Color:       c,
+		})
+	}
+	return node
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace is a temporary module directory holding the files of an archive,
+// so that the go command can be run on them
+type Workspace struct {
+	Dir string
+}
+
+// NewWorkspace writes every file of the archive to a new temporary
+// directory. A go.mod is added if the archive has none.
+func (a *Archive) NewWorkspace() (*Workspace, error) {
+	dir, err := os.MkdirTemp("", "goastviewer-*")
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{Dir: dir}
+
+	hasGoMod := false
+	for _, file := range a.Txtar.Files {
+		if !filepath.IsLocal(file.Name) {
+			w.Close()
+			return nil, fmt.Errorf("file name %q is not a local path", file.Name)
+		}
+		if file.Name == "go.mod" {
+			hasGoMod = true
+		}
+		if err := w.writeFile(file.Name, file.Data); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	if !hasGoMod {
+		versions := goVersions()
+		goMod := fmt.Sprintf("module example.com/archive\n\ngo %s\n", strings.TrimPrefix(versions[len(versions)-1], "go"))
+		if err := w.writeFile("go.mod", []byte(goMod)); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (w *Workspace) writeFile(name string, data []byte) error {
+	path := filepath.Join(w.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Command returns a go command running in the workspace with the local
+// toolchain
+func (w *Workspace) Command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = w.Dir
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local", "GOFLAGS=-mod=mod")
+	return cmd
+}
+
+// Close removes the workspace directory
+func (w *Workspace) Close() error {
+	return os.RemoveAll(w.Dir)
+}